@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clock tells the time of the entries
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which only moves when told to, to get reproducible entries in tests
+type ManualClock struct {
+	mux sync.Mutex
+	now time.Time
+}
+
+// NewManualClock instantiates and returns a ManualClock frozen at t
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{now: t}
+}
+
+// Now implements Clock
+func (c *ManualClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to t
+func (c *ManualClock) Set(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.now = t
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// WithClock creates a copy of a Log that tells the time of the entries with c
+func (l *Log) WithClock(c Clock) *Log {
+	n := l.With(Fields{})
+	n.clock = c
+	return n
+}
+
+// WithTrimmedPaths creates a copy of a Log that reports the file paths relative to root,
+// e.g. the root of the module, in the report and source locations and in the stacktraces
+func (l *Log) WithTrimmedPaths(root string) *Log {
+	n := l.With(Fields{})
+	n.trimPrefix = ""
+	if root != "" {
+		n.trimPrefix = strings.TrimRight(root, "/") + "/"
+	}
+	return n
+}
+
+// WithStacktrace creates a copy of a Log that does or does not print out the stacktrace of the
+// ERROR and CRITICAL entries. Stacktraces hold goroutine ids and memory addresses, disabling them
+// makes the error entries reproducible.
+func (l *Log) WithStacktrace(enabled bool) *Log {
+	n := l.With(Fields{})
+	n.noStacktrace = !enabled
+	return n
+}
+
+func (l *Log) trimPath(path string) string {
+	if l.trimPrefix == "" {
+		return path
+	}
+
+	return strings.TrimPrefix(path, l.trimPrefix)
+}
+
+func (l *Log) trimPaths(s string) string {
+	if l.trimPrefix == "" {
+		return s
+	}
+
+	return strings.Replace(s, l.trimPrefix, "", -1)
+}
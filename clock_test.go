@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	clock.Advance(time.Minute)
+	if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected %s, got %s", start.Add(time.Minute), got)
+	}
+
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("expected %s, got %s", start, got)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	_, file, _, _ := runtime.Caller(0)
+	clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	buf := new(bytes.Buffer)
+	log := New().
+		With(Fields{"key": "value"}).
+		WithOutput(buf).
+		WithClock(clock).
+		WithTrimmedPaths(filepath.Dir(file)).
+		WithStacktrace(false).
+		WithSourceLocation(true)
+
+	log.Info("INFO message")
+	clock.Advance(time.Second)
+	log.Warn("WARN message")
+	clock.Advance(time.Second)
+	log.Error("ERROR message")
+	timer := log.Timer("db_query")
+	clock.Advance(1500 * time.Millisecond)
+	timer.Stop()
+
+	golden := filepath.Join("testdata", "golden.jsonl")
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to update %s: %s", golden, err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", golden, err)
+	}
+	if got := buf.String(); got != string(expected) {
+		t.Errorf("output does not match %s, run the tests with -update if the change is expected:\n%s", golden, got)
+	}
+}
+
+func TestWithTrimmedPathsInStacktrace(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Dir(file)
+
+	buf := new(bytes.Buffer)
+	New().WithOutput(buf).WithTrimmedPaths(dir).Error("ERROR message")
+
+	got := buf.String()
+	if bytes.Contains(buf.Bytes(), []byte(dir+"/")) {
+		t.Errorf("output %s should not contain %s", got, dir)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"stacktrace":"goroutine`)) || !bytes.Contains(buf.Bytes(), []byte(`\tclock_test.go:`)) {
+		t.Errorf("output %s should contain a stacktrace with trimmed paths", got)
+	}
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+// Operation groups related entries in Cloud Logging, e.g. the ones of a long-running job
+type Operation struct {
+	ID       string `json:"id,omitempty"`
+	Producer string `json:"producer,omitempty"`
+	First    bool   `json:"first,omitempty"`
+	Last     bool   `json:"last,omitempty"`
+}
+
+// SourceLocation is the location in the source code of the statement emitting an entry
+type SourceLocation struct {
+	File     string `json:"file,omitempty"`
+	Line     string `json:"line,omitempty"`
+	Function string `json:"function,omitempty"`
+}
+
+var (
+	idPrefix  = newIDPrefix()
+	idCounter uint64
+)
+
+// newIDPrefix returns a random prefix, so that the generated ids are unique across processes
+func newIDPrefix() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		fmt.Printf("logger ERROR: cannot generate the id prefix: %s\n", err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// insertID returns a new insertId if they are enabled, an empty string otherwise.
+// The ids of a process are sortable, so that entries sharing the same timestamp keep their order.
+func (l *Log) insertID() string {
+	if !l.insertIDs {
+		return ""
+	}
+
+	return newID()
+}
+
+// newID returns a new id, unique across processes and sortable within a process
+func newID() string {
+	return fmt.Sprintf("%s-%016x", idPrefix, atomic.AddUint64(&idCounter, 1))
+}
+
+// WithLabels creates a copy of a Log with added Cloud Logging labels
+func (l *Log) WithLabels(labels map[string]string) *Log {
+	n := l.With(Fields{})
+	n.labels = copyLabels(l.labels)
+	if n.labels == nil {
+		n.labels = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		n.labels[k] = v
+	}
+	return n
+}
+
+// WithOperation creates a copy of a Log whose entries belong to the operation identified by id
+// and producer, so that they are grouped in the Cloud Logging console
+func (l *Log) WithOperation(id, producer string) *Log {
+	n := l.With(Fields{})
+	n.operation = &Operation{
+		ID:       id,
+		Producer: producer,
+	}
+	return n
+}
+
+// WithSourceLocation creates a copy of a Log that annotates every entry, not only the errors,
+// with the location of the caller in the source code
+func (l *Log) WithSourceLocation(enabled bool) *Log {
+	n := l.With(Fields{})
+	n.sourceLocation = enabled
+	return n
+}
+
+// WithInsertID creates a copy of a Log that sets a unique insertId on every entry,
+// which Cloud Logging uses to deduplicate and order entries
+func (l *Log) WithInsertID(enabled bool) *Log {
+	n := l.With(Fields{})
+	n.insertIDs = enabled
+	return n
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+
+	return c
+}
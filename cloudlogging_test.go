@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWithLabelsAndOperation(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	parent := New().WithOutput(buf).WithLabels(map[string]string{"team": "core"})
+	log := parent.
+		WithLabels(map[string]string{"job": "backfill"}).
+		WithOperation("op-1", "backfill")
+
+	log.Info("INFO message")
+	expected := `"logging.googleapis.com/labels":{"job":"backfill","team":"core"},"logging.googleapis.com/operation":{"id":"op-1","producer":"backfill"}}`
+	if got := strings.TrimRight(buf.String(), "\n"); !strings.HasSuffix(got, expected) {
+		t.Errorf("output %s does not end with %s", got, expected)
+	}
+	buf.Reset()
+
+	// Labels added to a derived logger do not leak into the parent
+	parent.Info("INFO message")
+	expected = `"logging.googleapis.com/labels":{"team":"core"}}`
+	if got := strings.TrimRight(buf.String(), "\n"); !strings.HasSuffix(got, expected) {
+		t.Errorf("output %s does not end with %s", got, expected)
+	}
+}
+
+func TestWithInsertID(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithInsertID(true)
+
+	log.Info("first")
+	log.Warn("second")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	ids := make([]string, len(lines))
+	for i, line := range lines {
+		p := Payload{}
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("failed to unmarshal payload: %s", err)
+		}
+		ids[i] = p.InsertID
+	}
+
+	if ids[0] == "" || ids[0] >= ids[1] {
+		t.Errorf("expected sorted and unique insertIds, got %v", ids)
+	}
+
+	buf.Reset()
+	log.WithInsertID(false).Info("no id")
+	if strings.Contains(buf.String(), "insertId") {
+		t.Errorf("output %s should not contain an insertId", buf)
+	}
+}
+
+func TestWithSourceLocation(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSourceLocation(true)
+
+	// Every entry point must report the line calling it
+	_, file, line, _ := runtime.Caller(0)
+	log.Info("Info")
+	log.Infof("%s", "Infof")
+	log.Metric("Metric", 1, nil)
+	log.Counter("Counter", nil).Inc()
+	log.Timer("Timer").Stop()
+	log.Error("Error")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, l := range lines {
+		p := Payload{}
+		if err := json.Unmarshal([]byte(l), &p); err != nil {
+			t.Fatalf("failed to unmarshal payload: %s", err)
+		}
+
+		expected := SourceLocation{
+			File:     file,
+			Line:     strconv.Itoa(line + i + 1),
+			Function: "logger.TestWithSourceLocation",
+		}
+		if p.SourceLocation == nil || *p.SourceLocation != expected {
+			t.Errorf("%s: expected source location %+v, got %+v", p.Message, expected, p.SourceLocation)
+		}
+	}
+
+	if p := (Payload{}); json.Unmarshal([]byte(lines[len(lines)-1]), &p) == nil {
+		if p.Context.ReportLocation.LineNumber != line+len(lines) {
+			t.Errorf("expected the report location to match the source location, got %+v", p.Context.ReportLocation)
+		}
+	}
+}
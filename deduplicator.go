@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+const repeatCountField = "repeatCount"
+
+type deduplicatorKey struct {
+	message string
+	ReportLocation
+}
+
+type deduplicatorWindow struct {
+	start      time.Time
+	severity   severity
+	suppressed int
+}
+
+// deduplicatorReport is the number of entries suppressed within a window which is over
+type deduplicatorReport struct {
+	deduplicatorKey
+	severity   severity
+	suppressed int
+}
+
+// Deduplicator suppresses repeated error entries. Entries are considered identical when they
+// share the same message and report location: the first one is written, the following ones are
+// suppressed until the window is over. The number of entries suppressed in the meantime is then
+// reported in a repeatCount field, either by the first identical entry written after that or,
+// if the errors stopped, by an entry of its own written along with the next entry of the Log.
+type Deduplicator struct {
+	window time.Duration
+
+	mux   sync.Mutex
+	seen  map[deduplicatorKey]*deduplicatorWindow
+	swept time.Time
+	clock Clock
+}
+
+// NewDeduplicator instantiates and returns a Deduplicator which writes at most one identical
+// error entry per window. The same Deduplicator can be shared by several loggers.
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	return &Deduplicator{
+		window: window,
+		seen:   map[deduplicatorKey]*deduplicatorWindow{},
+		clock:  systemClock{},
+	}
+}
+
+// WithClock sets the clock measuring the windows and returns the Deduplicator
+func (d *Deduplicator) WithClock(c Clock) *Deduplicator {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.clock = c
+	return d
+}
+
+// check reports whether an entry should be written and, if so, how many identical entries were
+// suppressed since the last one was.
+func (d *Deduplicator) check(sev severity, message string, location *ReportLocation) (bool, int) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	now := d.clock.Now()
+
+	key := deduplicatorKey{message: message, ReportLocation: *location}
+	w, ok := d.seen[key]
+	if ok && now.Sub(w.start) < d.window {
+		w.suppressed++
+		return false, 0
+	}
+
+	repeats := 0
+	if ok {
+		repeats = w.suppressed
+	}
+	d.seen[key] = &deduplicatorWindow{start: now, severity: sev}
+
+	return true, repeats
+}
+
+// sweep forgets the windows which are over, and returns the number of entries they suppressed
+// for the caller to report them. It runs at most once per window, so a window is reported
+// within a window of its end.
+func (d *Deduplicator) sweep() []deduplicatorReport {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	now := d.clock.Now()
+	if now.Sub(d.swept) < d.window {
+		return nil
+	}
+
+	var reports []deduplicatorReport
+	for k, w := range d.seen {
+		if now.Sub(w.start) < d.window {
+			continue
+		}
+		if w.suppressed > 0 {
+			reports = append(reports, deduplicatorReport{
+				deduplicatorKey: k,
+				severity:        w.severity,
+				suppressed:      w.suppressed,
+			})
+		}
+		delete(d.seen, k)
+	}
+	d.swept = now
+
+	return reports
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeduplicator(time.Minute).WithClock(clock)
+
+	buf := new(bytes.Buffer)
+	log := New().With(Fields{"key": "value"}).WithOutput(buf).WithDeduplicator(d)
+
+	fail := func(msg string) {
+		log.Error(msg)
+	}
+
+	for i := 0; i < 5; i++ {
+		fail("downstream unavailable")
+	}
+	fail("another error")
+	log.Error("downstream unavailable")
+
+	got := buf.String()
+	if n := strings.Count(got, `"message":"downstream unavailable"`); n != 2 {
+		t.Errorf("expected 2 entries from different locations, got %d: %s", n, got)
+	}
+	if n := strings.Count(got, `"message":"another error"`); n != 1 {
+		t.Errorf("expected 1 entry with a different message, got %d: %s", n, got)
+	}
+	if strings.Contains(got, repeatCountField) {
+		t.Errorf("output should not contain %q yet: %s", repeatCountField, got)
+	}
+
+	clock.Advance(time.Minute)
+	buf.Reset()
+	fail("downstream unavailable")
+
+	p := struct {
+		Context struct {
+			Data Fields
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Context.Data[repeatCountField] != float64(4) {
+		t.Errorf("expected a repeat count of 4, got %v", p.Context.Data[repeatCountField])
+	}
+	if p.Context.Data["key"] != "value" {
+		t.Errorf("expected the logger fields to be kept, got %v", p.Context.Data)
+	}
+
+	// The repeat count must not leak into the logger fields
+	buf.Reset()
+	log.Warn("WARN message")
+	if strings.Contains(buf.String(), repeatCountField) {
+		t.Errorf("output should not contain %q: %s", repeatCountField, buf)
+	}
+}
+
+func TestDeduplicatorIgnoresNonErrors(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithDeduplicator(NewDeduplicator(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		log.Warn("WARN message")
+	}
+
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("expected 3 entries, got %d", n)
+	}
+}
+
+func TestDeduplicatorReportsEndedWindows(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	d := NewDeduplicator(time.Minute).WithClock(clock)
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(clock).WithOutput(buf).WithDeduplicator(d).WithStacktrace(false)
+
+	for i := 0; i < 4; i++ {
+		log.Error("downstream unavailable")
+	}
+
+	// The errors stopped: the count is reported along with the next entry once the window is over
+	clock.Advance(time.Minute)
+	buf.Reset()
+	log.Info("INFO message")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a report and an entry, got %d lines: %s", len(lines), buf)
+	}
+
+	p := struct {
+		Severity string
+		Message  string
+		Context  struct {
+			Data           Fields
+			ReportLocation *ReportLocation
+		}
+	}{}
+	if err := json.Unmarshal([]byte(lines[0]), &p); err != nil {
+		t.Fatalf("failed to unmarshal report: %s", err)
+	}
+	if p.Severity != "ERROR" || p.Message != "downstream unavailable" || p.Context.Data[repeatCountField] != float64(3) {
+		t.Errorf("unexpected report %s", lines[0])
+	}
+	if p.Context.ReportLocation == nil || p.Context.ReportLocation.FunctionName != "logger.TestDeduplicatorReportsEndedWindows" {
+		t.Errorf("expected the location of the errors, got %s", lines[0])
+	}
+
+	// The window is forgotten and reported once
+	if len(d.seen) != 0 {
+		t.Errorf("expected the windows to be forgotten, got %d", len(d.seen))
+	}
+	clock.Advance(time.Minute)
+	buf.Reset()
+	log.Info("INFO message")
+	if strings.Contains(buf.String(), repeatCountField) {
+		t.Errorf("output should not contain %q: %s", repeatCountField, buf)
+	}
+}
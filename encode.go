@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"unicode/utf8"
+)
+
+const (
+	// encodeErrorSuffix is appended to the key of a value which cannot be encoded, to report why
+	encodeErrorSuffix = "Error"
+	// maxEncodeDepth bounds the nesting of the maps and slices in the field values
+	maxEncodeDepth = 32
+	// logValuerMark stands in the path for the LogValuer values, which bound their nesting
+	// without being containers
+	logValuerMark = ^uintptr(0)
+)
+
+// LogValuer is implemented by the types which control how they appear in the entries, e.g. to
+// only write out the attributes of a domain type which are neither sensitive nor bulky.
+// LogValue usually returns Fields; its result is encoded like any other field value, so it can
+// hold LogValuer values in turn. LogValuer values are honored within Fields, maps and slices.
+type LogValuer interface {
+	LogValue() interface{}
+}
+
+var logValuerType = reflect.TypeOf((*LogValuer)(nil)).Elem()
+
+var (
+	errMaxDepth = errors.New("logger: maximum depth exceeded")
+	errCycle    = errors.New("logger: cyclic value")
+)
+
+// normalizeFields returns fields ready to be marshaled: LogValuer values are replaced by their
+// LogValue, errors are written out as their Error() string, fmt.Stringer values as their
+// String() one, valid UTF-8 []byte as a string, while json.Marshaler and encoding.TextMarshaler
+// values are left to encoding/json.
+// In strict mode, used once marshaling the entry has failed, the values which still cannot be
+// marshaled are replaced by their %v representation, and the reason is reported under their key
+// suffixed by encodeErrorSuffix, unless the fields already hold such a key. fields itself is
+// never modified, it is copied when needed.
+func normalizeFields(fields Fields, strict bool) Fields {
+	return normalizeMap(fields, strict, nil)
+}
+
+// normalizeMap normalizes the values of fields. path holds the maps and slices containing
+// fields, to detect the cycles.
+func normalizeMap(fields map[string]interface{}, strict bool, path []uintptr) Fields {
+	var out Fields
+	for k, v := range fields {
+		n, changed, err := normalize(v, strict, path)
+		if !changed && err == nil {
+			continue
+		}
+		if out == nil {
+			out = make(Fields, len(fields)+1)
+			for k, v := range fields {
+				out[k] = v
+			}
+		}
+		out[k] = n
+		// Never overwrite a field of the user with the marker
+		if _, exists := fields[k+encodeErrorSuffix]; err != nil && !exists {
+			out[k+encodeErrorSuffix] = err.Error()
+		}
+	}
+	if out == nil {
+		return Fields(fields)
+	}
+	return out
+}
+
+// normalize returns the value to marshal in place of v, whether it differs from v and, in strict
+// mode, why v cannot be marshaled
+func normalize(v interface{}, strict bool, path []uintptr) (interface{}, bool, error) {
+	if v == nil {
+		return nil, false, nil
+	}
+
+	switch t := v.(type) {
+	case LogValuer:
+		if isNilPointer(v) {
+			return nil, true, nil
+		}
+		if len(path) >= maxEncodeDepth {
+			return errMaxDepth.Error(), true, errMaxDepth
+		}
+		lv, err := safeLogValue(t)
+		if err != nil {
+			return lv, true, err
+		}
+		n, _, err := normalize(lv, strict, append(path, logValuerMark))
+		return n, true, err
+	case json.Marshaler, encoding.TextMarshaler:
+		return checkMarshal(v, strict)
+	case error:
+		if isNilPointer(v) {
+			return nil, true, nil
+		}
+		return safeString(v, t.Error), true, nil
+	case fmt.Stringer:
+		if isNilPointer(v) {
+			return nil, true, nil
+		}
+		return safeString(v, t.String), true, nil
+	case []byte:
+		if utf8.Valid(t) {
+			return string(t), true, nil
+		}
+		return v, false, nil
+	case Fields, map[string]interface{}, []interface{}:
+		return normalizeContainer(v, strict, path)
+	case float64:
+		return checkFloat(v, t)
+	case float32:
+		return checkFloat(v, float64(t))
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, false, nil
+	}
+
+	if holdsLogValuers(v) {
+		return normalizeContainer(toContainer(v), strict, path)
+	}
+	return checkMarshal(v, strict)
+}
+
+// holdsLogValuers reports whether v is a slice, an array or a map with string keys whose
+// elements may be LogValuer values
+func holdsLogValuers(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return false
+		}
+	case reflect.Slice, reflect.Array:
+	default:
+		return false
+	}
+
+	e := t.Elem()
+	return e.Kind() == reflect.Interface || e.Implements(logValuerType) ||
+		(t.Kind() == reflect.Slice && reflect.PtrTo(e).Implements(logValuerType))
+}
+
+// toContainer copies the slice, array or map v to a []interface{} or a map[string]interface{}
+func toContainer(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map {
+		if rv.IsNil() {
+			return map[string]interface{}(nil)
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return m
+	}
+
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return []interface{}(nil)
+	}
+	// The elements of a slice are addressable, so that LogValue methods with a pointer
+	// receiver can be called
+	addr := rv.Kind() == reflect.Slice && !rv.Type().Elem().Implements(logValuerType) &&
+		reflect.PtrTo(rv.Type().Elem()).Implements(logValuerType)
+	s := make([]interface{}, rv.Len())
+	for i := range s {
+		if addr {
+			s[i] = rv.Index(i).Addr().Interface()
+		} else {
+			s[i] = rv.Index(i).Interface()
+		}
+	}
+	return s
+}
+
+// safeLogValue calls LogValue, reporting a panic as an error
+func safeLogValue(v LogValuer) (lv interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("logger: LogValue panicked: %v", r)
+			lv = err.Error()
+		}
+	}()
+	return v.LogValue(), nil
+}
+
+// normalizeContainer normalizes the maps and slices, which can be cyclic
+func normalizeContainer(v interface{}, strict bool, path []uintptr) (interface{}, bool, error) {
+	p := reflect.ValueOf(v).Pointer()
+	for _, q := range path {
+		if p == q {
+			return errCycle.Error(), true, errCycle
+		}
+	}
+	if len(path) >= maxEncodeDepth {
+		return errMaxDepth.Error(), true, errMaxDepth
+	}
+	path = append(path, p)
+
+	switch t := v.(type) {
+	case Fields:
+		n := normalizeMap(t, strict, path)
+		return n, !sameMap(n, t), nil
+	case map[string]interface{}:
+		n := normalizeMap(t, strict, path)
+		return n, !sameMap(n, t), nil
+	}
+	return normalizeSlice(v.([]interface{}), strict, path)
+}
+
+func normalizeSlice(s []interface{}, strict bool, path []uintptr) (interface{}, bool, error) {
+	var (
+		out      []interface{}
+		firstErr error
+	)
+	for i, v := range s {
+		n, changed, err := normalize(v, strict, path)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make([]interface{}, len(s))
+			copy(out, s)
+		}
+		out[i] = n
+	}
+	if out == nil {
+		return s, false, firstErr
+	}
+	return out, true, firstErr
+}
+
+// checkMarshal falls back to the %v representation of v if it cannot be marshaled, in strict mode
+func checkMarshal(v interface{}, strict bool) (interface{}, bool, error) {
+	if !strict {
+		return v, false, nil
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%v", v), true, err
+	}
+	return v, false, nil
+}
+
+func checkFloat(v interface{}, f float64) (interface{}, bool, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprintf("%v", v), true, fmt.Errorf("json: unsupported value: %v", v)
+	}
+	return v, false, nil
+}
+
+// safeString calls f, falling back to the %v representation of v, which reports the panic, if
+// f panics
+func safeString(v interface{}, f func() string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("%v", v)
+		}
+	}()
+	return f()
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// sameMap reports whether a and b are the same map
+func sameMap(a, b map[string]interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
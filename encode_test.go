@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stringer struct{ name string }
+
+func (s stringer) String() string {
+	return "stringer " + s.name
+}
+
+type panicking struct{}
+
+func (panicking) String() string {
+	panic("boom")
+}
+
+type cyclic struct {
+	Name string
+	Next *cyclic
+}
+
+func TestSafeSerialization(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	var nilErr *net.OpError
+
+	buf := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(buf).With(Fields{
+		"error":    errors.New("downstream unavailable"),
+		"nilError": nilErr,
+		"stringer": stringer{"a"},
+		"time":     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"ip":       net.IPv4(127, 0, 0, 1),
+		"text":     []byte("plain text"),
+		"binary":   []byte{0xff, 0xfe},
+		"nested":   Fields{"list": []interface{}{errors.New("nested"), 1}},
+	}).Info("INFO message")
+
+	expected := `{"binary":"//4=","error":"downstream unavailable","ip":"127.0.0.1","nested":{"list":["nested",1]},"nilError":null,"stringer":"stringer a","text":"plain text","time":"2020-01-01T00:00:00Z"}`
+	if got := contextData(t, buf.Bytes()); got != expected {
+		t.Errorf("output %s does not match expected %s", got, expected)
+	}
+}
+
+func TestUnsupportedValues(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	loop := &cyclic{Name: "loop"}
+	loop.Next = loop
+
+	self := map[string]interface{}{}
+	self["self"] = self
+
+	buf := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(buf).With(Fields{
+		"key":      "value",
+		"nan":      math.NaN(),
+		"channel":  make(chan int),
+		"function": func(int) {},
+		"cyclic":   loop,
+		"self":     self,
+		"panic":    panicking{},
+	}).Info("INFO message")
+
+	if buf.Len() == 0 {
+		t.Fatalf("expected the entry to be written")
+	}
+	data := contextData(t, buf.Bytes())
+	for _, s := range []string{
+		`"key":"value"`,
+		`"nan":"NaN","nanError":"json: unsupported value: NaN"`,
+		`"channelError":"json: unsupported type: chan int"`,
+		`"functionError":"json: unsupported type: func(int)"`,
+		`"cyclic":"\u0026{loop 0x`,
+		`"cyclicError":"json: unsupported value: encountered a cycle`,
+		`"self":{"self":"logger: cyclic value","selfError":"logger: cyclic value"}`,
+		`"panic":"%!v(PANIC=String method: boom)"`,
+	} {
+		if !strings.Contains(data, s) {
+			t.Errorf("output %s should contain %s", data, s)
+		}
+	}
+}
+
+func TestNormalizeFieldsKeepsUnchangedFields(t *testing.T) {
+	f := Fields{"key": "value", "nested": Fields{"n": 1}}
+	if n := normalizeFields(f, true); !sameMap(n, f) {
+		t.Errorf("expected the fields to be left as they are, got %v", n)
+	}
+
+	f = Fields{"error": errors.New("failed")}
+	if n := normalizeFields(f, false); sameMap(n, f) || n["error"] != "failed" {
+		t.Errorf("unexpected fields %v", n)
+	}
+	if _, ok := f["error"].(error); !ok {
+		t.Errorf("the fields must not be modified, got %v", f)
+	}
+}
+
+type user struct {
+	ID    int
+	Email string
+}
+
+func (u *user) LogValue() interface{} {
+	return Fields{"id": u.ID}
+}
+
+type order struct {
+	ID    string
+	Buyer *user
+	Items []string
+}
+
+func (o order) LogValue() interface{} {
+	return Fields{"id": o.ID, "buyer": o.Buyer, "items": len(o.Items)}
+}
+
+type selfValuer struct{}
+
+func (s selfValuer) LogValue() interface{} {
+	return s
+}
+
+func TestLogValuer(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	u := &user{ID: 42, Email: "jane@example.com"}
+
+	buf := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(buf).With(Fields{
+		"user":    u,
+		"order":   order{ID: "o-1", Buyer: u, Items: []string{"a", "b"}},
+		"users":   []user{{ID: 1}, {ID: 2}},
+		"byName":  map[string]*user{"jane": u},
+		"list":    []interface{}{u, "plain"},
+		"nilUser": (*user)(nil),
+		"self":    selfValuer{},
+	}).Info("INFO message")
+
+	data := contextData(t, buf.Bytes())
+	if strings.Contains(data, "jane@example.com") {
+		t.Errorf("output %s should not contain the email", data)
+	}
+	for _, s := range []string{
+		`"user":{"id":42}`,
+		`"order":{"buyer":{"id":42},"id":"o-1","items":2}`,
+		`"users":[{"id":1},{"id":2}]`,
+		`"byName":{"jane":{"id":42}}`,
+		`"list":[{"id":42},"plain"]`,
+		`"nilUser":null`,
+		`"selfError":"logger: maximum depth exceeded"`,
+	} {
+		if !strings.Contains(data, s) {
+			t.Errorf("output %s should contain %s", data, s)
+		}
+	}
+}
+
+func TestEncodeErrorKeepsUserFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(buf).With(Fields{
+		"rate":      math.NaN(),
+		"rateError": "user",
+	}).Info("INFO message")
+
+	if got := contextData(t, buf.Bytes()); got != `{"rate":"NaN","rateError":"user"}` {
+		t.Errorf("expected the user field to be kept, got %s", got)
+	}
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// reportedErrorEventType types the entries which Error Reporting must pick up whatever their
+// severity or the presence of a stacktrace
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+const (
+	// userField is the field of the entries holding the user when none was set through WithUser
+	userField = "user"
+	// errorMessageField holds the message of an entry reported in an error group
+	errorMessageField = "errorMessage"
+	// urlField is the key the URL of the HTTP request is redacted as
+	urlField = "url"
+)
+
+// WithErrorGroup creates a copy of a Log whose errors are grouped by key in Error Reporting,
+// rather than by their stacktrace, e.g. for the same logical error logged from several call
+// paths or with varying messages. Error Reporting groups the events without stacktrace by their
+// message and location, so the reported entries of the Log, its ERROR and CRITICAL ones
+// included, are written without a stacktrace, with key as their message and report location.
+// Their message is moved to the errorMessage field and the location of the caller to the
+// source location of the entry.
+func (l *Log) WithErrorGroup(key string) *Log {
+	n := l.With(Fields{})
+	n.errorGroup = key
+	return n
+}
+
+func reportedType(entry *Entry) string {
+	if entry.Reported {
+		return reportedErrorEventType
+	}
+	return ""
+}
+
+// groupError makes the error group of the Log the only thing the entry is grouped by
+func (l *Log) groupError(entry *Entry) {
+	fields := make(Fields, len(entry.Fields)+1)
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+	fields[errorMessageField] = entry.Message
+	entry.Fields = fields
+
+	if entry.SourceLocation == nil && entry.ReportLocation != nil {
+		entry.SourceLocation = &SourceLocation{
+			File:     entry.ReportLocation.FilePath,
+			Line:     strconv.Itoa(entry.ReportLocation.LineNumber),
+			Function: entry.ReportLocation.FunctionName,
+		}
+	}
+
+	entry.Message = l.errorGroup
+	entry.ReportLocation = &ReportLocation{
+		FilePath:     l.errorGroup,
+		FunctionName: l.errorGroup,
+	}
+}
+
+// reportedUser returns the user affected by the entry, redacted as the userField: the one set
+// through WithUser or, for the entries reported to Error Reporting, which counts the affected
+// users, the userField of the entry if any
+func (l *Log) reportedUser(entry *Entry) string {
+	user := l.user
+	if user == "" && entry.Reported {
+		switch u := entry.Fields[userField].(type) {
+		case string:
+			user = u
+		case int, int32, int64, uint, uint32, uint64, float64:
+			user = fmt.Sprint(u)
+		}
+	}
+
+	if user == "" || l.redactor == nil {
+		return user
+	}
+	return l.redactor.redactField(userField, user)
+}
+
+// reportedHTTPRequest returns the HTTP request of the Log with its URL redacted
+func (l *Log) reportedHTTPRequest() *HTTPRequest {
+	if l.httpRequest == nil || l.redactor == nil {
+		return l.httpRequest
+	}
+
+	r := *l.httpRequest
+	r.URL = redactURL(l.redactor, r.URL)
+	return &r
+}
+
+// redactURL redacts the query parameters of s as the fields named after them, then the whole
+// URL as the urlField
+func redactURL(r *Redactor, s string) string {
+	u, err := url.Parse(s)
+	if err == nil && u.RawQuery != "" {
+		q := u.Query()
+		for k, values := range q {
+			for i, v := range values {
+				values[i] = r.redactField(k, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+		s = u.String()
+	}
+	return r.redactField(urlField, s)
+}
+
+// Report prints out a message with the passed severity level, and reports it to Error Reporting
+// whatever the severity: the entry is typed as a ReportedErrorEvent and annotated with the
+// location of the caller, so that e.g. a handled error logged as a warning, without a
+// stacktrace, still shows up in Error Reporting.
+func (l *Log) Report(severity Severity, message string) {
+	if !l.Enabled(severity) {
+		return
+	}
+
+	l.logEntry(0, &Entry{
+		Severity: severity,
+		Message:  message,
+		Reported: true,
+	})
+}
+
+// HTTPRequest is the HTTP request an error occurred in, as shown by Error Reporting
+type HTTPRequest struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int    `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+// WithUser creates a copy of a Log whose entries are about user, e.g. a user id, so that
+// Error Reporting counts the users affected by an error
+func (l *Log) WithUser(user string) *Log {
+	n := l.With(Fields{})
+	n.user = user
+	return n
+}
+
+// WithHTTPRequest creates a copy of a Log whose entries occurred in r, so that Error Reporting
+// shows the request of an error. status is the status code of the response, if known, 0
+// otherwise. The remote IP is taken from the X-Forwarded-For header, set by the load balancers,
+// if any. The URL is redacted by the Redactor of the Log, if any, its query parameters as the
+// fields named after them. A nil r leaves the request of the Log as it is.
+func (l *Log) WithHTTPRequest(r *http.Request, status int) *Log {
+	n := l.With(Fields{})
+	if r == nil {
+		return n
+	}
+	n.httpRequest = &HTTPRequest{
+		Method:             r.Method,
+		URL:                requestURL(r),
+		UserAgent:          r.UserAgent(),
+		Referrer:           r.Referer(),
+		ResponseStatusCode: status,
+		RemoteIP:           remoteIP(r),
+	}
+	return n
+}
+
+// requestURL returns the absolute URL of r, the URL of a server request being relative
+func requestURL(r *http.Request) string {
+	if r.URL == nil {
+		return ""
+	}
+	if r.URL.IsAbs() || r.Host == "" {
+		return r.URL.String()
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func remoteIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return strings.TrimSpace(strings.Split(xff, ",")[0])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
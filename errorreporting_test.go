@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf)
+
+	log.Report(WARN, "retrying after a timeout")
+
+	p := struct {
+		Severity   string
+		Type       string `json:"@type"`
+		Stacktrace string
+		Context    struct {
+			ReportLocation *ReportLocation
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Severity != "WARN" || p.Type != reportedErrorEventType || p.Stacktrace != "" {
+		t.Errorf("unexpected entry %s", buf)
+	}
+	if loc := p.Context.ReportLocation; loc == nil || loc.FunctionName != "logger.TestReport" || !strings.HasSuffix(loc.FilePath, "errorreporting_test.go") {
+		t.Errorf("unexpected report location %+v", loc)
+	}
+
+	buf.Reset()
+	log.WithLevel(ERROR).Report(WARN, "filtered out")
+	if buf.Len() != 0 {
+		t.Errorf("expected the WARN entry to be filtered out, got %s", buf)
+	}
+
+	// The other entries are left untyped
+	log.Error("ERROR message")
+	if strings.Contains(buf.String(), "@type") {
+		t.Errorf("output should not contain @type: %s", buf)
+	}
+}
+
+func TestWithErrorGroup(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithErrorGroup("db-down")
+
+	// The same logical error logged from two call paths, with varying messages
+	var queryLine, retryLine int
+	query := func(id int) {
+		_, _, queryLine, _ = runtime.Caller(0)
+		log.Errorf("query %d failed", id)
+	}
+	retry := func(id int) {
+		_, _, retryLine, _ = runtime.Caller(0)
+		log.Report(WARN, fmt.Sprintf("retry of query %d failed", id))
+	}
+	query(1)
+	query(2)
+	retry(3)
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(lines))
+	}
+
+	// Everything Error Reporting groups the events by is built from the key
+	expected := `"@type":"` + reportedErrorEventType + `","eventTime":"2020-01-01T00:00:00.123456789Z",` +
+		`"message":"db-down","serviceContext":{"service":"my-app","version":"1.0"},` +
+		`"context":{"data":{"errorMessage":"%s"},"reportLocation":{"filePath":"db-down","functionName":"db-down","lineNumber":0}},` +
+		`"logging.googleapis.com/sourceLocation":{"file":"%s","line":"%d","function":"%s"}}`
+	_, file, _, _ := runtime.Caller(0)
+	sites := []struct {
+		message  string
+		line     int
+		function string
+	}{
+		{"query 1 failed", queryLine + 1, "logger.TestWithErrorGroup.func1"},
+		{"query 2 failed", queryLine + 1, "logger.TestWithErrorGroup.func1"},
+		{"retry of query 3 failed", retryLine + 1, "logger.TestWithErrorGroup.func2"},
+	}
+	for i, site := range sites {
+		if e := fmt.Sprintf(expected, site.message, file, site.line, site.function); !strings.HasSuffix(lines[i], e) {
+			t.Errorf("output %s does not match expected string %s", lines[i], e)
+		}
+		if strings.Contains(lines[i], "stacktrace") {
+			t.Errorf("expected no stacktrace, got %s", lines[i])
+		}
+	}
+
+	// The entries which are not reported are left as they are
+	buf.Reset()
+	log.Info("INFO message")
+	if !strings.Contains(buf.String(), `"message":"INFO message"`) || strings.Contains(buf.String(), "@type") {
+		t.Errorf("expected the entry to be left untouched, got %s", buf)
+	}
+}
+
+func TestReportedUser(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf)
+
+	log.With(Fields{userField: 42}).Report(WARN, "retrying after a timeout")
+	if !strings.Contains(buf.String(), `"user":"42"`) {
+		t.Errorf("expected the user to be reported, got %s", buf)
+	}
+
+	buf.Reset()
+	log.WithUser("user-7").With(Fields{userField: 42}).Report(WARN, "retrying after a timeout")
+	if !strings.Contains(buf.String(), `"user":"user-7"`) {
+		t.Errorf("expected the user set through WithUser to be reported, got %s", buf)
+	}
+
+	// The entries which are not reported are left as they are
+	buf.Reset()
+	log.With(Fields{userField: 42}).Warn("WARN message")
+	if strings.Contains(buf.String(), `"user":"42"`) {
+		t.Errorf("expected no user in the context, got %s", buf)
+	}
+}
+
+func TestWithUserAndHTTPRequest(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	r := httptest.NewRequest("POST", "/orders?id=1", nil)
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("Referer", "https://example.com/cart")
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithStacktrace(false)
+
+	log.WithUser("user-42").WithHTTPRequest(r, 500).Error("order failed")
+
+	p := struct {
+		Context struct {
+			User        string
+			HTTPRequest json.RawMessage
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Context.User != "user-42" {
+		t.Errorf("unexpected user %q", p.Context.User)
+	}
+	expected := `{"method":"POST","url":"http://example.com/orders?id=1","userAgent":"test-agent","referrer":"https://example.com/cart","responseStatusCode":500,"remoteIp":"10.0.0.1"}`
+	if string(p.Context.HTTPRequest) != expected {
+		t.Errorf("output %s does not match expected %s", p.Context.HTTPRequest, expected)
+	}
+
+	buf.Reset()
+	log.Error("ERROR message")
+	if strings.Contains(buf.String(), `"user"`) || strings.Contains(buf.String(), "httpRequest") {
+		t.Errorf("the parent Log should be left untouched: %s", buf)
+	}
+}
+
+func TestUserAndHTTPRequestRedacted(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	r := NewRedactor(RedactionPolicy{
+		Keys:          []string{"token"},
+		ValuePatterns: []*regexp.Regexp{regexp.MustCompile(`[a-z]+@[a-z]+\.com`)},
+		Action:        Mask,
+	})
+
+	req := httptest.NewRequest("GET", "/users/jane@example.com?token=secret&page=2", nil)
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithRedactor(r).WithStacktrace(false)
+	log.WithUser("jane@example.com").WithHTTPRequest(req, 0).Error("profile failed")
+
+	p := struct {
+		Context struct {
+			User        string
+			HTTPRequest HTTPRequest
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Context.User != redactedValue {
+		t.Errorf("expected the user to be redacted, got %q", p.Context.User)
+	}
+	if expected := "http://example.com/users/[REDACTED]?page=2&token=%5BREDACTED%5D"; p.Context.HTTPRequest.URL != expected {
+		t.Errorf("unexpected URL %q, expected %q", p.Context.HTTPRequest.URL, expected)
+	}
+}
+
+func TestWithHTTPRequestNil(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithStacktrace(false)
+
+	log.WithHTTPRequest(nil, 0).Error("ERROR message")
+	if strings.Contains(buf.String(), "httpRequest") {
+		t.Errorf("output %s should not contain an HTTP request", buf)
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.2")
+
+	if ip := remoteIP(r); ip != "203.0.113.7" {
+		t.Errorf("expected the client IP of X-Forwarded-For, got %q", ip)
+	}
+}
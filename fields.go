@@ -0,0 +1,240 @@
+package logger
+
+import "sync"
+
+// loggerLabel is the label reporting the name of a named Log
+const loggerLabel = "logger"
+
+// LazyValue is a field value computed only when an entry carrying it is written, so that
+// expensive values cost nothing when the entry is filtered out by the level, the sampler,
+// the deduplicator or a hook. A plain func() interface{} field value is treated the same way.
+// The values nested in Fields, e.g. in groups, are resolved as well.
+type LazyValue func() interface{}
+
+// fieldSet holds the fields added by a call to With, or the keys removed by a call to Without,
+// on top of the ones of the parent Log, under the group path of the Log. Deriving a Log only
+// stores the changes: the whole set is flattened once, the first time the Log writes an entry.
+type fieldSet struct {
+	parent  *fieldSet
+	group   []string
+	fields  Fields
+	removed []string
+	lazy    bool
+
+	once sync.Once
+	flat Fields
+}
+
+func newFieldSet(parent *fieldSet, group []string, fields Fields) *fieldSet {
+	s := &fieldSet{
+		parent: parent,
+		group:  group,
+		fields: make(Fields, len(fields)),
+	}
+	if parent != nil {
+		s.lazy = parent.lazy
+	}
+	for k, v := range fields {
+		s.fields[k] = v
+		s.lazy = s.lazy || isLazy(v)
+	}
+	return s
+}
+
+// all returns the flattened fields, which must not be modified
+func (s *fieldSet) all() Fields {
+	s.once.Do(func() {
+		if s.parent == nil && len(s.group) == 0 {
+			s.flat = s.fields
+			return
+		}
+
+		var parent Fields
+		if s.parent != nil {
+			parent = s.parent.all()
+		}
+		s.flat = make(Fields, len(parent)+len(s.fields))
+		for k, v := range parent {
+			s.flat[k] = v
+		}
+		if len(s.removed) > 0 {
+			removeAt(s.flat, s.group, s.removed)
+		}
+		// A set created by Without has no fields, merging them would add an empty group
+		if len(s.fields) > 0 {
+			mergeAt(s.flat, s.group, s.fields)
+		}
+	})
+	return s.flat
+}
+
+// mergeAt adds fields to dst under the group path. The groups met along the path are copied,
+// as they may belong to the parent set.
+func mergeAt(dst Fields, group []string, fields Fields) {
+	if len(group) == 0 {
+		for k, v := range fields {
+			dst[k] = v
+		}
+		return
+	}
+
+	parent, _ := dst[group[0]].(Fields)
+	sub := make(Fields, len(parent)+len(fields))
+	for k, v := range parent {
+		sub[k] = v
+	}
+	mergeAt(sub, group[1:], fields)
+	dst[group[0]] = sub
+}
+
+// removeAt removes keys from dst under the group path, copying the groups met along the path
+func removeAt(dst Fields, group []string, keys []string) {
+	if len(group) == 0 {
+		for _, k := range keys {
+			delete(dst, k)
+		}
+		return
+	}
+
+	parent, ok := dst[group[0]].(Fields)
+	if !ok {
+		return
+	}
+	sub := make(Fields, len(parent))
+	for k, v := range parent {
+		sub[k] = v
+	}
+	removeAt(sub, group[1:], keys)
+	dst[group[0]] = sub
+}
+
+// copyFields returns a deep copy of fields: the nested Fields, maps and slices are copied too
+func copyFields(fields Fields) Fields {
+	return copyValue(fields, 0).(Fields)
+}
+
+func copyValue(v interface{}, depth int) interface{} {
+	// Cyclic values are left shared past the maximum depth
+	if depth > maxEncodeDepth {
+		return v
+	}
+
+	switch t := v.(type) {
+	case Fields:
+		f := make(Fields, len(t))
+		for k, v := range t {
+			f[k] = copyValue(v, depth+1)
+		}
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[k] = copyValue(v, depth+1)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, v := range t {
+			s[i] = copyValue(v, depth+1)
+		}
+		return s
+	}
+	return v
+}
+
+func isLazy(v interface{}) bool {
+	switch v := v.(type) {
+	case LazyValue, func() interface{}:
+		return true
+	case Fields:
+		for _, v := range v {
+			if isLazy(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveLazy returns a copy of fields with the lazy values, groups included, replaced by
+// their result
+func resolveLazy(fields Fields) Fields {
+	f := make(Fields, len(fields))
+	for k, v := range fields {
+		switch fn := v.(type) {
+		case LazyValue:
+			f[k] = fn()
+		case func() interface{}:
+			f[k] = fn()
+		case Fields:
+			f[k] = resolveLazy(fn)
+		default:
+			f[k] = v
+		}
+	}
+	return f
+}
+
+// Enabled reports whether an entry with the passed severity would be written, so that callers
+// can skip building expensive messages or fields. ERROR and CRITICAL entries are always written.
+func (l *Log) Enabled(s Severity) bool {
+	return s >= ERROR || l.isValidLogLevel(s)
+}
+
+// WithGroup creates a copy of a Log whose fields added from then on are nested under name in
+// the entry context, e.g. to keep the fields of a library apart from the ones of the caller.
+// Groups nest, and an empty group is left out of the entries.
+func (l *Log) WithGroup(name string) *Log {
+	n := l.With(Fields{})
+	if name != "" {
+		n.group = append(l.group[:len(l.group):len(l.group)], name)
+	}
+	return n
+}
+
+// Named creates a copy of a Log for a named component. The name is appended to the one of the
+// Log with a dot, reported in the "logger" label, and opens a group where the fields of the
+// component are nested.
+func (l *Log) Named(name string) *Log {
+	if name == "" {
+		return l.With(Fields{})
+	}
+
+	full := name
+	if l.name != "" {
+		full = l.name + "." + name
+	}
+
+	n := l.WithGroup(name).WithLabels(map[string]string{loggerLabel: full})
+	n.name = full
+	return n
+}
+
+// withRootFields creates a copy of a Log with fields added at the root of the entry context,
+// whatever its group, for the keys which must stay on stable paths
+func (l *Log) withRootFields(fields Fields) *Log {
+	n := l.With(Fields{})
+	if len(fields) > 0 {
+		n.fields = newFieldSet(l.fields, nil, fields)
+	}
+	return n
+}
+
+// Without creates a copy of a Log without the passed keys of the current group, e.g. to strip
+// sensitive fields before handing the Log to another component
+func (l *Log) Without(keys ...string) *Log {
+	n := l.With(Fields{})
+	if len(keys) > 0 {
+		n.fields = newFieldSet(l.fields, l.group, nil)
+		n.fields.removed = append([]string(nil), keys...)
+	}
+	return n
+}
+
+// WithoutFields creates a copy of a Log without any field. Its group is kept, so the fields
+// added from then on are still nested under it.
+func (l *Log) WithoutFields() *Log {
+	n := l.With(Fields{})
+	n.fields = newFieldSet(nil, nil, nil)
+	return n
+}
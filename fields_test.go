@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLazyFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	calls := 0
+	expensive := func() interface{} {
+		calls++
+		return "computed"
+	}
+
+	buf := new(bytes.Buffer)
+	log := New().WithLevel(INFO).WithOutput(buf).With(Fields{
+		"plain": expensive,
+		"typed": LazyValue(expensive),
+	})
+
+	log.Debug("DEBUG message")
+	if calls != 0 || buf.Len() != 0 {
+		t.Fatalf("lazy values must not be resolved for filtered entries, got %d calls", calls)
+	}
+
+	log.Info("INFO message")
+	if calls != 2 {
+		t.Errorf("expected both lazy values to be resolved, got %d calls", calls)
+	}
+
+	p := struct {
+		Context struct {
+			Data Fields
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Context.Data["plain"] != "computed" || p.Context.Data["typed"] != "computed" {
+		t.Errorf("unexpected fields %v", p.Context.Data)
+	}
+
+	// The next entry resolves the values again
+	log.Info("INFO message")
+	if calls != 4 {
+		t.Errorf("expected the lazy values to be resolved per entry, got %d calls", calls)
+	}
+}
+
+func TestWithKeepsParentFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	parent := New().With(Fields{"a": 1, "b": 2})
+	child := parent.With(Fields{"b": 3, "c": 4})
+	same := child.WithLevel(WARN)
+
+	if got := parent.getFields(); len(got) != 2 || got["b"] != 2 {
+		t.Errorf("unexpected parent fields %v", got)
+	}
+	if got := child.getFields(); len(got) != 3 || got["a"] != 1 || got["b"] != 3 || got["c"] != 4 {
+		t.Errorf("unexpected child fields %v", got)
+	}
+	if same.fields != child.fields {
+		t.Errorf("a copy without new fields should share the fields of its parent")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	log := New().WithLevel(WARN)
+	cases := map[severity]bool{
+		DEBUG:    false,
+		INFO:     false,
+		WARN:     true,
+		ERROR:    true,
+		CRITICAL: true,
+	}
+	for s, expected := range cases {
+		if got := log.Enabled(s); got != expected {
+			t.Errorf("Enabled(%s) = %t, expected %t", s, got, expected)
+		}
+	}
+
+	if !New().WithLevel(CRITICAL).Enabled(ERROR) {
+		t.Errorf("ERROR entries are always written")
+	}
+}
+
+func BenchmarkDisabledDebugf(b *testing.B) {
+	initConfig(INFO, "my-app", "1.0")
+
+	log := New().With(Fields{"key": "value"})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		log.Debugf("message %d", i)
+	}
+}
+
+func TestWithGroup(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"id": "request"})
+
+	db := log.WithGroup("db").With(Fields{"id": "query"})
+	db.WithGroup("pool").With(Fields{"size": 10}).WithGroup("empty").With(Fields{"id": "conn"}).Info("INFO message")
+
+	expected := `{"db":{"id":"query","pool":{"empty":{"id":"conn"},"size":10}},"id":"request"}`
+	if got := contextData(t, buf.Bytes()); got != expected {
+		t.Errorf("output %s does not match expected %s", got, expected)
+	}
+
+	// An empty group is left out, and the parent logger is left untouched
+	buf.Reset()
+	log.WithGroup("unused").Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"id":"request"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	db.With(Fields{"table": "users"}).Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"id":"query","table":"users"},"id":"request"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf)
+
+	log.Named("db").Named("pool").With(Fields{"size": 10}).Info("INFO message")
+
+	p := struct {
+		Labels  map[string]string `json:"logging.googleapis.com/labels"`
+		Context struct {
+			Data json.RawMessage
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Labels[loggerLabel] != "db.pool" {
+		t.Errorf("unexpected labels %v", p.Labels)
+	}
+	if string(p.Context.Data) != `{"db":{"pool":{"size":10}}}` {
+		t.Errorf("unexpected fields %s", p.Context.Data)
+	}
+}
+
+// contextData returns the context data of the entry b as JSON
+func contextData(t *testing.T, b []byte) string {
+	t.Helper()
+
+	p := struct {
+		Context struct {
+			Data json.RawMessage
+		}
+	}{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	return string(p.Context.Data)
+}
+
+func TestWithout(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"user": 42, "request": "abc"})
+	db := log.WithGroup("db").With(Fields{"user": "admin", "query": "select"})
+
+	log.Without("user", "missing").Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"request":"abc"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	// Without removes the keys of the current group only
+	buf.Reset()
+	db.Without("user").With(Fields{"table": "users"}).Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"query":"select","table":"users"},"request":"abc","user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	// A removed key can be added back, and the parent logger is left untouched
+	buf.Reset()
+	log.Without("user").With(Fields{"user": 43}).Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"request":"abc","user":43}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	db.Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"query":"select","user":"admin"},"request":"abc","user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+}
+
+func TestWithoutFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"user": 42}).WithGroup("db")
+
+	log.WithoutFields().Info("INFO message")
+	if strings.Contains(buf.String(), `"data"`) {
+		t.Errorf("expected no fields, got %s", buf)
+	}
+
+	buf.Reset()
+	log.WithoutFields().With(Fields{"query": "select"}).Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"query":"select"}}` {
+		t.Errorf("expected the group to be kept, got %s", got)
+	}
+}
+
+func TestFieldsOrder(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	a := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(a).
+		With(Fields{"b": 1, "a": 2}).WithGroup("g").With(Fields{"z": 1, "y": Fields{"d": 1, "c": 2}}).
+		Info("INFO message")
+
+	// Build the same fields in a different order
+	b := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(b).
+		With(Fields{"a": 2}).With(Fields{"b": 1}).WithGroup("g").With(Fields{"y": Fields{"c": 2, "d": 1}}).With(Fields{"z": 1}).
+		Info("INFO message")
+
+	if a.String() != b.String() {
+		t.Errorf("expected the same line, got %s and %s", a, b)
+	}
+	if got := contextData(t, a.Bytes()); got != `{"a":2,"b":1,"g":{"y":{"c":2,"d":1},"z":1}}` {
+		t.Errorf("expected sorted keys, got %s", got)
+	}
+}
+
+func TestWithoutInGroup(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"user": 42})
+
+	// Removing a key from an empty group does not add the group
+	log.WithGroup("db").Without("user").Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	log.WithGroup("db").With(Fields{"query": "select"}).WithGroup("tx").Without("query").Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"query":"select"},"user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+}
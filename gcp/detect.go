@@ -0,0 +1,198 @@
+// Package gcp detects the metadata of the GCP environment a process runs in (Cloud Run, GKE,
+// GCE), so that it can be attached to every entry as labels:
+//
+//	res, err := gcp.Detect(ctx, gcp.Config{})
+//	log := logger.New().WithLabels(res.Labels)
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultEndpoint = "http://metadata.google.internal"
+	defaultTimeout  = 2 * time.Second
+
+	// metadataHostEnv overrides the metadata server host, as for the Google Cloud client libraries
+	metadataHostEnv = "GCE_METADATA_HOST"
+)
+
+// Types of the monitored resources detected
+const (
+	CloudRunRevision = "cloud_run_revision"
+	K8sContainer     = "k8s_container"
+	GCEInstance      = "gce_instance"
+)
+
+// Labels set on the detected resource, named after the Cloud Logging monitored resource labels
+const (
+	ProjectIDLabel         = "project_id"
+	ZoneLabel              = "zone"
+	InstanceIDLabel        = "instance_id"
+	InstanceNameLabel      = "instance_name"
+	ServiceNameLabel       = "service_name"
+	RevisionNameLabel      = "revision_name"
+	ConfigurationNameLabel = "configuration_name"
+	ClusterNameLabel       = "cluster_name"
+	NamespaceNameLabel     = "namespace_name"
+	PodNameLabel           = "pod_name"
+	ContainerNameLabel     = "container_name"
+)
+
+// Environment variables read to detect the resource. The Kubernetes ones are expected to be set
+// through the downward API.
+var envLabels = []struct {
+	env, label string
+}{
+	{"K_SERVICE", ServiceNameLabel},
+	{"K_REVISION", RevisionNameLabel},
+	{"K_CONFIGURATION", ConfigurationNameLabel},
+	{"POD_NAMESPACE", NamespaceNameLabel},
+	{"POD_NAME", PodNameLabel},
+	{"CONTAINER_NAME", ContainerNameLabel},
+}
+
+// Paths of the metadata server queried to detect the resource
+var metadataLabels = []struct {
+	path, label string
+}{
+	{"project/project-id", ProjectIDLabel},
+	{"instance/zone", ZoneLabel},
+	{"instance/id", InstanceIDLabel},
+	{"instance/name", InstanceNameLabel},
+	{"instance/attributes/cluster-name", ClusterNameLabel},
+}
+
+// Config configures the detection
+type Config struct {
+	// Endpoint is the base URL of the metadata server, $GCE_METADATA_HOST or
+	// http://metadata.google.internal by default
+	Endpoint string
+	// Client queries the metadata server, a client with a 2s timeout by default
+	Client *http.Client
+	// SkipMetadataServer restricts the detection to the environment variables
+	SkipMetadataServer bool
+}
+
+// Resource is the detected environment
+type Resource struct {
+	// Type is the monitored resource type, empty if the environment is not recognised
+	Type string
+	// Labels describe the resource, e.g. project_id, zone or pod_name
+	Labels map[string]string
+}
+
+// Detect detects the resource the process runs on from the environment variables and the
+// metadata server. The detection is best-effort: if the metadata server cannot be queried the
+// error is returned along with the resource detected from the environment variables.
+func Detect(ctx context.Context, cfg Config) (*Resource, error) {
+	res := &Resource{Labels: map[string]string{}}
+
+	for _, e := range envLabels {
+		if v := os.Getenv(e.env); v != "" {
+			res.Labels[e.label] = v
+		}
+	}
+
+	var err error
+	if !cfg.SkipMetadataServer {
+		err = newClient(cfg).labels(ctx, res.Labels)
+	}
+
+	switch {
+	case res.Labels[ServiceNameLabel] != "":
+		res.Type = CloudRunRevision
+	case res.Labels[PodNameLabel] != "" || os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		res.Type = K8sContainer
+	case res.Labels[InstanceIDLabel] != "":
+		res.Type = GCEInstance
+	}
+
+	return res, err
+}
+
+// client queries the metadata server
+type client struct {
+	endpoint string
+	http     *http.Client
+}
+
+func newClient(cfg Config) *client {
+	c := &client{
+		endpoint: cfg.Endpoint,
+		http:     cfg.Client,
+	}
+
+	if c.endpoint == "" {
+		c.endpoint = defaultEndpoint
+		if host := os.Getenv(metadataHostEnv); host != "" {
+			c.endpoint = "http://" + host
+		}
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: defaultTimeout}
+	}
+
+	return c
+}
+
+// labels sets the labels available on the metadata server. Missing values are skipped, the
+// detection stops at the first failing request.
+func (c *client) labels(ctx context.Context, labels map[string]string) error {
+	for _, m := range metadataLabels {
+		v, ok, err := c.get(ctx, m.path)
+		if err != nil {
+			return err
+		}
+		if !ok || v == "" {
+			continue
+		}
+
+		// The zone comes as projects/<project-number>/zones/<zone>
+		if m.label == ZoneLabel {
+			v = v[strings.LastIndex(v, "/")+1:]
+		}
+		labels[m.label] = v
+	}
+
+	return nil
+}
+
+// get returns the value at path, and whether it exists
+func (c *client) get(ctx context.Context, path string) (string, bool, error) {
+	url := strings.TrimRight(c.endpoint, "/") + "/computeMetadata/v1/" + path
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: cannot create metadata request: %s", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: cannot query the metadata server: %s", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", false, fmt.Errorf("gcp: cannot read %s from the metadata server: %s", path, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return strings.TrimSpace(string(b)), true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	}
+
+	return "", false, fmt.Errorf("gcp: metadata server responded %s for %s", resp.Status, path)
+}
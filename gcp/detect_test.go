@@ -0,0 +1,146 @@
+package gcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// setenv sets the environment variables, clears the other ones read by Detect and returns a
+// function restoring them
+func setenv(vars map[string]string) func() {
+	all := map[string]string{"KUBERNETES_SERVICE_HOST": ""}
+	for _, e := range envLabels {
+		all[e.env] = ""
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+
+	old := map[string]*string{}
+	for k, v := range all {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+
+	return func() {
+		for k, v := range old {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+// metadataServer is a local stand-in for the GCE metadata server
+func metadataServer(t *testing.T, values map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("missing Metadata-Flavor header on %s", r.URL.Path)
+		}
+
+		v, ok := values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}))
+}
+
+func TestDetectGCEInstance(t *testing.T) {
+	defer setenv(nil)()
+
+	srv := metadataServer(t, map[string]string{
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/zone":      "projects/1234/zones/europe-west1-b",
+		"/computeMetadata/v1/instance/id":        "42",
+		"/computeMetadata/v1/instance/name":      "my-instance",
+	})
+	defer srv.Close()
+
+	res, err := Detect(context.Background(), Config{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	expected := &Resource{
+		Type: GCEInstance,
+		Labels: map[string]string{
+			ProjectIDLabel:    "my-project",
+			ZoneLabel:         "europe-west1-b",
+			InstanceIDLabel:   "42",
+			InstanceNameLabel: "my-instance",
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v, got %+v", expected, res)
+	}
+}
+
+func TestDetectCloudRun(t *testing.T) {
+	defer setenv(map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00042", "K_CONFIGURATION": "api"})()
+
+	srv := metadataServer(t, map[string]string{
+		"/computeMetadata/v1/project/project-id": "my-project",
+	})
+	defer srv.Close()
+
+	res, err := Detect(context.Background(), Config{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	expected := &Resource{
+		Type: CloudRunRevision,
+		Labels: map[string]string{
+			ProjectIDLabel:         "my-project",
+			ServiceNameLabel:       "api",
+			RevisionNameLabel:      "api-00042",
+			ConfigurationNameLabel: "api",
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v, got %+v", expected, res)
+	}
+}
+
+func TestDetectGKEWithoutMetadataServer(t *testing.T) {
+	defer setenv(map[string]string{"POD_NAME": "api-7d9f", "POD_NAMESPACE": "prod", "CONTAINER_NAME": "api"})()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	res, err := Detect(context.Background(), Config{Endpoint: srv.URL})
+	if err == nil {
+		t.Errorf("expected the metadata server error to be returned")
+	}
+
+	expected := &Resource{
+		Type: K8sContainer,
+		Labels: map[string]string{
+			PodNameLabel:       "api-7d9f",
+			NamespaceNameLabel: "prod",
+			ContainerNameLabel: "api",
+		},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v, got %+v", expected, res)
+	}
+
+	res, err = Detect(context.Background(), Config{Endpoint: srv.URL, SkipMetadataServer: true})
+	if err != nil || !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %+v, got %+v and %v", expected, res, err)
+	}
+}
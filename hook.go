@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Severity is the level of a log entry, one of DEBUG, INFO, WARN, ERROR and CRITICAL
+type Severity = severity
+
+// ErrDropEntry can be returned by a Hook to prevent an entry from being written
+var ErrDropEntry = errors.New("logger: entry dropped by hook")
+
+// Entry is a log entry about to be written, as handed to the hooks
+type Entry struct {
+	Severity       Severity
+	Time           time.Time
+	Message        string
+	Fields         Fields
+	Labels         map[string]string
+	Operation      *Operation
+	Metric         *Metric
+	Stacktrace     string
+	ReportLocation *ReportLocation
+	SourceLocation *SourceLocation
+	// Reported is set for the entries typed as a ReportedErrorEvent, see Log.Report
+	Reported bool
+}
+
+// Hook is fired for every entry of the listed severities before it is written.
+// Fire can add or change the entry fields, trigger side effects, or return ErrDropEntry to
+// veto the entry. Any other error is reported and the entry is written anyway.
+// The entry fields, nested ones included, are a copy: changing them leaves the Log untouched.
+// Hooks can be fired concurrently by the goroutines sharing a Log: they must be safe for
+// concurrent use, and must not log through the Log firing them.
+type Hook interface {
+	Levels() []Severity
+	Fire(*Entry) error
+}
+
+// fireHooks fires the hooks registered for the entry severity, in order.
+// It returns false if one of them vetoed the entry.
+func (l *Log) fireHooks(entry *Entry) bool {
+	for _, h := range l.hooks {
+		if !hookFiresOn(h, entry.Severity) {
+			continue
+		}
+
+		err := h.Fire(entry)
+		if err == ErrDropEntry {
+			return false
+		}
+		if err != nil {
+			fmt.Printf("logger ERROR: hook failed: %s\n", err)
+		}
+	}
+
+	return true
+}
+
+func hookFiresOn(h Hook, s Severity) bool {
+	for _, lvl := range h.Levels() {
+		if lvl == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testHook struct {
+	levels []Severity
+	fire   func(*Entry) error
+	fired  int
+}
+
+func (h *testHook) Levels() []Severity {
+	return h.levels
+}
+
+func (h *testHook) Fire(e *Entry) error {
+	h.fired++
+	return h.fire(e)
+}
+
+func TestHookAddsFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	hook := &testHook{
+		levels: []Severity{INFO},
+		fire: func(e *Entry) error {
+			e.Fields["pod"] = "pod-1"
+			return nil
+		},
+	}
+
+	log := New().With(Fields{"key": "value"}).WithOutput(buf).WithHook(hook)
+
+	log.Info("INFO message")
+	if !strings.Contains(buf.String(), `"context":{"data":{"key":"value","pod":"pod-1"}}`) {
+		t.Errorf("output %s does not contain the hook field", buf)
+	}
+	buf.Reset()
+
+	// Hooks only fire on their levels and never alter the fields of the Log
+	log.Warn("WARN message")
+	if !strings.Contains(buf.String(), `"context":{"data":{"key":"value"}}`) {
+		t.Errorf("output %s should only contain the logger fields", buf)
+	}
+	buf.Reset()
+
+	// Hooks are inherited by With
+	log.With(Fields{"foo": "bar"}).Info("INFO message")
+	if !strings.Contains(buf.String(), `"context":{"data":{"foo":"bar","key":"value","pod":"pod-1"}}`) {
+		t.Errorf("output %s does not contain the hook field", buf)
+	}
+
+	if hook.fired != 2 {
+		t.Errorf("expected the hook to be fired twice, got %d", hook.fired)
+	}
+}
+
+func TestHookDropsEntry(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	veto := &testHook{
+		levels: []Severity{DEBUG, INFO},
+		fire: func(e *Entry) error {
+			if e.Message == "noisy" {
+				return ErrDropEntry
+			}
+			return nil
+		},
+	}
+	next := &testHook{
+		levels: []Severity{DEBUG, INFO},
+		fire:   func(*Entry) error { return nil },
+	}
+
+	log := New().WithOutput(buf).WithHook(veto).WithHook(next)
+
+	log.Info("noisy")
+	log.Info("useful")
+
+	got := buf.String()
+	if strings.Contains(got, "noisy") {
+		t.Errorf("output %s should not contain the vetoed entry", got)
+	}
+	if !strings.Contains(got, "useful") {
+		t.Errorf("output %s should contain the entry", got)
+	}
+	if next.fired != 1 {
+		t.Errorf("expected hooks after a veto not to be fired, got %d calls", next.fired)
+	}
+}
+
+func TestWithHookDoesNotAffectParent(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	var fired []string
+	hook := func(name string) Hook {
+		return &testHook{
+			levels: []Severity{INFO},
+			fire: func(*Entry) error {
+				fired = append(fired, name)
+				return nil
+			},
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	parent := New().WithOutput(buf).WithHook(hook("parent"))
+	a := parent.WithHook(hook("a"))
+	parent.WithHook(hook("b"))
+
+	a.Info("INFO message")
+	parent.Info("INFO message")
+
+	if got := strings.Join(fired, ","); got != "parent,a,parent" {
+		t.Errorf("unexpected hooks fired: %s", got)
+	}
+}
+
+func TestHookCannotChangeNestedFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	hook := &testHook{
+		levels: []Severity{INFO},
+		fire: func(e *Entry) error {
+			e.Fields["db"].(Fields)["injected"] = true
+			e.Fields["user"].(map[string]interface{})["injected"] = true
+			return nil
+		},
+	}
+
+	parent := New().WithClock(testClock).WithOutput(buf).
+		With(Fields{"user": map[string]interface{}{"id": 42}}).
+		WithGroup("db").With(Fields{"table": "users"})
+
+	parent.WithHook(hook).Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"injected":true,"table":"users"},"user":{"id":42,"injected":true}}` {
+		t.Errorf("expected the hook to change the entry, got %s", got)
+	}
+
+	buf.Reset()
+	parent.Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"table":"users"},"user":{"id":42}}` {
+		t.Errorf("the fields of the Log must be left untouched, got %s", got)
+	}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import "os"
+
+// badKeysField lists the elements of the key/value pairs which are not a valid key
+const badKeysField = "badKeys"
+
+// pairsToFields turns alternating keys and values into Fields. An element expected to be a key
+// which is not a string, or a last key without a value, is listed under badKeysField: the
+// pairing goes on from the next element, nothing panics.
+func pairsToFields(keysAndValues []interface{}) Fields {
+	fields := make(Fields, len(keysAndValues)/2)
+
+	var bad []interface{}
+	for i := 0; i < len(keysAndValues); {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			bad = append(bad, keysAndValues[i])
+			i++
+			continue
+		}
+
+		fields[key] = keysAndValues[i+1]
+		i += 2
+	}
+
+	if len(bad) > 0 {
+		fields[badKeysField] = bad
+	}
+
+	return fields
+}
+
+// Debugw prints out a message with DEBUG severity level, with fields given as alternating
+// keys and values
+func (l *Log) Debugw(message string, keysAndValues ...interface{}) {
+	if !l.isValidLogLevel(DEBUG) {
+		return
+	}
+
+	l.logPairs(DEBUG, message, keysAndValues)
+}
+
+// Infow prints out a message with INFO severity level, with fields given as alternating
+// keys and values
+func (l *Log) Infow(message string, keysAndValues ...interface{}) {
+	if !l.isValidLogLevel(INFO) {
+		return
+	}
+
+	l.logPairs(INFO, message, keysAndValues)
+}
+
+// Warnw prints out a message with WARN severity level, with fields given as alternating
+// keys and values
+func (l *Log) Warnw(message string, keysAndValues ...interface{}) {
+	if !l.isValidLogLevel(WARN) {
+		return
+	}
+
+	l.logPairs(WARN, message, keysAndValues)
+}
+
+// Errorw prints out a message with ERROR severity level, with fields given as alternating
+// keys and values
+func (l *Log) Errorw(message string, keysAndValues ...interface{}) {
+	l.logPairs(ERROR, message, keysAndValues)
+}
+
+// Fatalw is equivalent to Errorw() followed by a call to os.Exit(1).
+// It prints out a message with CRITICAL severity level
+func (l *Log) Fatalw(message string, keysAndValues ...interface{}) {
+	l.logPairs(CRITICAL, message, keysAndValues)
+	os.Exit(1)
+}
+
+// logPairs must be called straight from the exported methods for the caller to be
+// reported correctly
+func (l *Log) logPairs(severity severity, message string, keysAndValues []interface{}) {
+	l.logEntry(1, &Entry{
+		Severity: severity,
+		Message:  message,
+		Fields:   pairsToFields(keysAndValues),
+	})
+}
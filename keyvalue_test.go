@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPairsToFields(t *testing.T) {
+	cases := []struct {
+		pairs    []interface{}
+		expected Fields
+	}{
+		{nil, Fields{}},
+		{[]interface{}{"a", 1, "b", "two"}, Fields{"a": 1, "b": "two"}},
+		{[]interface{}{"a", 1, "dangling"}, Fields{"a": 1, badKeysField: []interface{}{"dangling"}}},
+		{[]interface{}{42, "a", 1}, Fields{"a": 1, badKeysField: []interface{}{42}}},
+		{[]interface{}{"a", nil, nil}, Fields{"a": nil, badKeysField: []interface{}{nil}}},
+	}
+
+	for _, c := range cases {
+		if got := pairsToFields(c.pairs); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("pairsToFields(%v) = %v, expected %v", c.pairs, got, c.expected)
+		}
+	}
+}
+
+func TestKeyValueMethods(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"request": "abc"})
+
+	log.Infow("order created", "user", 42, "order", "o-1")
+	if got := contextData(t, buf.Bytes()); got != `{"order":"o-1","request":"abc","user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	log.Warnw("odd pairs", "user", 42, 3.5)
+	if got := contextData(t, buf.Bytes()); got != `{"badKeys":[3.5],"request":"abc","user":42}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	// The fields do not leak into the Log
+	buf.Reset()
+	log.Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"request":"abc"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	log.WithLevel(INFO).Debugw("DEBUG message", "user", 42)
+	if buf.Len() != 0 {
+		t.Errorf("expected the DEBUG entry to be filtered out, got %s", buf)
+	}
+
+	log.Errorw("failed", "user", 42)
+	if !strings.Contains(buf.String(), `"functionName":"logger.TestKeyValueMethods"`) {
+		t.Errorf("invalid function name in error log: %s", buf)
+	}
+}
+
+func BenchmarkInfow(b *testing.B) {
+	initConfig(INFO, "my-app", "1.0")
+
+	log := New().WithOutput(ioutil.Discard).With(Fields{"key": "value"})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		log.Infow("INFO message", "request", 42)
+	}
+}
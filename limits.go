@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+const (
+	// MaxEntrySize is the size of the largest entry accepted by Cloud Logging
+	MaxEntrySize = 256 * 1024
+
+	// truncatedMarker ends the truncated strings
+	truncatedMarker = "...(truncated)"
+	// truncatedField flags the entries which have been truncated
+	truncatedField = "truncated"
+
+	// minShrunkString is the size under which the strings are not shrunk any further to fit an
+	// entry in Limits.Entry, the fields are dropped instead
+	minShrunkString = 64
+	maxShrinkRounds = 8
+)
+
+// Limits caps the size in bytes of the entries. A zero limit means no limit.
+// The strings going over their limit are truncated and end with a marker, and the entry is
+// flagged with a "truncated" field, rather than being dropped.
+type Limits struct {
+	// Message caps the message
+	Message int
+	// StringField caps every string field value, the nested ones included
+	StringField int
+	// Stacktrace caps the stacktrace of the ERROR and CRITICAL entries
+	Stacktrace int
+	// Entry caps the whole encoded entry: the longest strings are shrunk until it fits
+	Entry int
+}
+
+// DefaultLimits are the limits of a new Log, which keep the entries within the limit of
+// Cloud Logging
+var DefaultLimits = Limits{Entry: MaxEntrySize}
+
+// WithLimits creates a copy of a Log whose entries are capped by limits
+func (l *Log) WithLimits(limits Limits) *Log {
+	n := l.With(Fields{})
+	n.limits = limits
+	return n
+}
+
+// limitEntry truncates the message, the string fields and the stacktrace of the entry
+func (l *Log) limitEntry(entry *Entry) {
+	truncated := false
+	if s, ok := truncate(entry.Message, l.limits.Message); ok {
+		entry.Message, truncated = s, true
+	}
+	if s, ok := truncate(entry.Stacktrace, l.limits.Stacktrace); ok {
+		entry.Stacktrace, truncated = s, true
+	}
+	if l.limits.StringField > 0 {
+		if f, ok := truncateValue(entry.Fields, l.limits.StringField); ok {
+			entry.Fields, truncated = f.(Fields), true
+		}
+	}
+	if truncated {
+		entry.Fields = flagTruncated(entry.Fields)
+	}
+}
+
+// shrink shrinks the longest strings of the payload until it fits in the entry limit, and
+// returns it marshaled. If the strings cannot be shrunk enough, the fields are dropped.
+func (l *Log) shrink(payload *Payload, b []byte) ([]byte, error) {
+	max := l.limits.Entry
+	for i := 0; i < maxShrinkRounds && len(b) > max; i++ {
+		// Cutting the excess off the longest string is enough unless the strings are inflated
+		// by the JSON escaping, otherwise shrink it in proportion
+		longest := longestString(payload)
+		limit := longest - (len(b) - max)
+		if limit < minShrunkString {
+			limit = longest * max / len(b)
+		}
+		if limit < minShrunkString {
+			break
+		}
+
+		payload.Message, _ = truncate(payload.Message, limit)
+		payload.Stacktrace, _ = truncate(payload.Stacktrace, limit)
+		if payload.Context != nil {
+			if f, ok := truncateValue(payload.Context.Data, limit); ok {
+				payload.Context.Data = f.(Fields)
+			}
+		}
+		l.flagPayload(payload, nil)
+
+		var err error
+		if b, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) <= max {
+		return b, nil
+	}
+
+	// Drop the fields and keep the strings which identify the entry
+	payload.Message, _ = truncate(payload.Message, max/4)
+	payload.Stacktrace, _ = truncate(payload.Stacktrace, max/4)
+	l.flagPayload(payload, Fields{})
+	return json.Marshal(payload)
+}
+
+// flagPayload flags the payload as truncated, replacing its fields by data if not nil
+func (l *Log) flagPayload(payload *Payload, data Fields) {
+	if payload.Context == nil {
+		payload.Context = &Context{}
+	}
+	if data == nil {
+		data = payload.Context.Data
+	}
+	payload.Context.Data = flagTruncated(data)
+}
+
+func flagTruncated(fields Fields) Fields {
+	if fields[truncatedField] == true {
+		return fields
+	}
+
+	f := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		f[k] = v
+	}
+	f[truncatedField] = true
+	return f
+}
+
+// truncate cuts s to max bytes, marker included, on a rune boundary
+func truncate(s string, max int) (string, bool) {
+	if max <= 0 || len(s) <= max {
+		return s, false
+	}
+
+	// The limit is too small for the marker, which is cut as well
+	cut := max - len(truncatedMarker)
+	if cut < 0 {
+		return truncatedMarker[:max], true
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + truncatedMarker, true
+}
+
+// truncateValue truncates the strings held by v, copying the maps and slices it changes
+func truncateValue(v interface{}, max int) (interface{}, bool) {
+	switch t := v.(type) {
+	case string:
+		return truncate(t, max)
+	case Fields:
+		if f, ok := truncateMap(t, max); ok {
+			return Fields(f), true
+		}
+	case map[string]interface{}:
+		return truncateMap(t, max)
+	case []interface{}:
+		var out []interface{}
+		for i, e := range t {
+			if n, ok := truncateValue(e, max); ok {
+				if out == nil {
+					out = append([]interface{}(nil), t...)
+				}
+				out[i] = n
+			}
+		}
+		if out != nil {
+			return out, true
+		}
+	case []string:
+		var out []string
+		for i, e := range t {
+			if n, ok := truncate(e, max); ok {
+				if out == nil {
+					out = append([]string(nil), t...)
+				}
+				out[i] = n
+			}
+		}
+		if out != nil {
+			return out, true
+		}
+	}
+	return v, false
+}
+
+func truncateMap(m map[string]interface{}, max int) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, v := range m {
+		if n, ok := truncateValue(v, max); ok {
+			if out == nil {
+				out = make(map[string]interface{}, len(m))
+				for k, v := range m {
+					out[k] = v
+				}
+			}
+			out[k] = n
+		}
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
+
+// longestString returns the length of the longest string of the payload which can be shrunk
+func longestString(payload *Payload) int {
+	n := len(payload.Message)
+	if len(payload.Stacktrace) > n {
+		n = len(payload.Stacktrace)
+	}
+	if payload.Context != nil {
+		if m := longestIn(payload.Context.Data); m > n {
+			n = m
+		}
+	}
+	return n
+}
+
+func longestIn(v interface{}) int {
+	n := 0
+	switch t := v.(type) {
+	case string:
+		n = len(t)
+	case Fields:
+		for _, e := range t {
+			if m := longestIn(e); m > n {
+				n = m
+			}
+		}
+	case map[string]interface{}:
+		for _, e := range t {
+			if m := longestIn(e); m > n {
+				n = m
+			}
+		}
+	case []interface{}:
+		for _, e := range t {
+			if m := longestIn(e); m > n {
+				n = m
+			}
+		}
+	case []string:
+		for _, e := range t {
+			if len(e) > n {
+				n = len(e)
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithLimits(Limits{
+		Message:     20,
+		StringField: 20,
+		Stacktrace:  100,
+	})
+
+	fields := Fields{
+		"short":  "value",
+		"body":   strings.Repeat("a", 100),
+		"nested": Fields{"list": []interface{}{strings.Repeat("b", 100)}},
+	}
+	log.With(fields).Error(strings.Repeat("é", 20))
+
+	p := struct {
+		Message    string
+		Stacktrace string
+		Context    struct {
+			Data Fields
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+
+	// The message is cut on a rune boundary
+	if p.Message != "ééé"+truncatedMarker {
+		t.Errorf("unexpected message %q", p.Message)
+	}
+	if len(p.Stacktrace) > 100 || !strings.HasSuffix(p.Stacktrace, truncatedMarker) {
+		t.Errorf("unexpected stacktrace %q", p.Stacktrace)
+	}
+
+	expected := `{"body":"aaaaaa...(truncated)","nested":{"list":["bbbbbb...(truncated)"]},"short":"value","truncated":true}`
+	if got := contextData(t, buf.Bytes()); got != expected {
+		t.Errorf("output %s does not match expected %s", got, expected)
+	}
+	if len(fields["body"].(string)) != 100 {
+		t.Errorf("the fields of the Log must not be modified")
+	}
+
+	buf.Reset()
+	log.Info("short message")
+	if strings.Contains(buf.String(), truncatedField) {
+		t.Errorf("the entry should not be truncated: %s", buf)
+	}
+}
+
+func TestEntryLimit(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf)
+
+	log.With(Fields{
+		"request":  "GET /",
+		"response": strings.Repeat("<a>", MaxEntrySize),
+	}).Info("INFO message")
+
+	if buf.Len() > MaxEntrySize+1 {
+		t.Fatalf("expected the entry to fit in %d bytes, got %d", MaxEntrySize, buf.Len())
+	}
+
+	p := struct {
+		Message string
+		Context struct {
+			Data Fields
+		}
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Message != "INFO message" || p.Context.Data["request"] != "GET /" || p.Context.Data[truncatedField] != true {
+		t.Errorf("unexpected entry %s %v", p.Message, p.Context.Data["request"])
+	}
+	if s, _ := p.Context.Data["response"].(string); !strings.HasSuffix(s, truncatedMarker) {
+		t.Errorf("expected the response to be truncated")
+	}
+}
+
+func TestEntryLimitDropsFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	fields := Fields{}
+	for _, k := range strings.Split("abcdefghijklmnopqrstuvwxyz", "") {
+		fields[k] = strings.Repeat(k, 40)
+	}
+
+	buf := new(bytes.Buffer)
+	New().WithClock(testClock).WithOutput(buf).WithLimits(Limits{Entry: 512}).With(fields).Info("INFO message")
+
+	if buf.Len() > 512+1 {
+		t.Errorf("expected the entry to fit in 512 bytes, got %d: %s", buf.Len(), buf)
+	}
+	if got := contextData(t, buf.Bytes()); got != `{"truncated":true}` {
+		t.Errorf("expected the fields to be dropped, got %s", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s, expected string
+		max         int
+	}{
+		{"short", "short", 10},
+		{"unlimited", "unlimited", 0},
+		{strings.Repeat("x", 21), "xxxxxx" + truncatedMarker, 20},
+		{strings.Repeat("x", 20), "...(t", 5},
+		{strings.Repeat("x", 20), truncatedMarker, len(truncatedMarker)},
+	}
+	for _, c := range cases {
+		got, _ := truncate(c.s, c.max)
+		if got != c.expected {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", c.s, c.max, got, c.expected)
+		}
+		if c.max > 0 && len(got) > c.max {
+			t.Errorf("truncate(%q, %d) = %q exceeds the limit", c.s, c.max, got)
+		}
+	}
+}
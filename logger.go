@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -44,7 +45,8 @@ var logLevelValue = map[string]severity{
 	"CRITICAL": CRITICAL,
 }
 
-// Fields is used to wrap the log entries payload
+// Fields is used to wrap the log entries payload. The keys are written out in sorted order,
+// groups included, so that the same fields always give the same line.
 type Fields map[string]interface{}
 
 // ServiceContext is required by the Stackdriver Error format
@@ -64,6 +66,8 @@ type ReportLocation struct {
 type Context struct {
 	Data           Fields          `json:"data,omitempty"`
 	ReportLocation *ReportLocation `json:"reportLocation,omitempty"`
+	User           string          `json:"user,omitempty"`
+	HTTPRequest    *HTTPRequest    `json:"httpRequest,omitempty"`
 }
 
 type trace struct {
@@ -74,25 +78,65 @@ type trace struct {
 
 // Payload groups all the data for a log entry
 type Payload struct {
-	Severity       string          `json:"severity"`
-	EventTime      string          `json:"eventTime"`
-	Caller         string          `json:"caller,omitempty"`
-	Message        string          `json:"message"`
-	ServiceContext *ServiceContext `json:"serviceContext,omitempty"`
-	Context        *Context        `json:"context,omitempty"`
-	Stacktrace     string          `json:"stacktrace,omitempty"`
+	Severity       string            `json:"severity"`
+	Type           string            `json:"@type,omitempty"`
+	EventTime      string            `json:"eventTime,omitempty"`
+	Timestamp      *Timestamp        `json:"timestamp,omitempty"`
+	Caller         string            `json:"caller,omitempty"`
+	Message        string            `json:"message"`
+	ServiceContext *ServiceContext   `json:"serviceContext,omitempty"`
+	Context        *Context          `json:"context,omitempty"`
+	Metric         *Metric           `json:"metric,omitempty"`
+	Stacktrace     string            `json:"stacktrace,omitempty"`
+	InsertID       string            `json:"logging.googleapis.com/insertId,omitempty"`
+	Labels         map[string]string `json:"logging.googleapis.com/labels,omitempty"`
+	Operation      *Operation        `json:"logging.googleapis.com/operation,omitempty"`
+	SourceLocation *SourceLocation   `json:"logging.googleapis.com/sourceLocation,omitempty"`
 	trace
 }
 
-// Log is the main type for the logger package
+// Log is the main type for the logger package. A Log is never modified once built: the With
+// methods return a copy, so a Log can be shared by goroutines without locking. Only its output
+// is synchronised.
 type Log struct {
-	level          severity
-	mux            sync.RWMutex
-	fields         Fields
-	serviceContext *ServiceContext
-	writer         io.Writer
-	callerSkip     int
-	trace          trace
+	level           severity
+	fields          *fieldSet
+	group           []string
+	name            string
+	serviceContext  *ServiceContext
+	output          *output
+	callerSkip      int
+	trace           trace
+	sampler         *Sampler
+	deduplicator    *Deduplicator
+	hooks           []Hook
+	redactor        *Redactor
+	metrics         Metrics
+	labels          map[string]string
+	operation       *Operation
+	sourceLocation  bool
+	insertIDs       bool
+	sinks           []Sink
+	clock           Clock
+	trimPrefix      string
+	noStacktrace    bool
+	timeLayout      string
+	utc             bool
+	timestampObject bool
+	limits          Limits
+	errorGroup      string
+	user            string
+	httpRequest     *HTTPRequest
+}
+
+// output serialises the writes of the loggers sharing the same writer
+type output struct {
+	mux sync.Mutex
+	w   io.Writer
+}
+
+func newOutput(w io.Writer) *output {
+	return &output{w: w}
 }
 
 var (
@@ -124,10 +168,13 @@ func initConfig(lvl severity, svc, ver string) {
 // New instantiates and returns a Log object
 func New() *Log {
 	l := &Log{
-		fields:     Fields{},
-		writer:     os.Stdout,
+		fields:     newFieldSet(nil, nil, nil),
+		output:     newOutput(os.Stdout),
 		level:      defaultLogLevel,
 		callerSkip: defaultCallerSkip,
+		clock:      systemClock{},
+		timeLayout: time.RFC3339Nano,
+		limits:     DefaultLimits,
 	}
 
 	if service != "" && version != "" {
@@ -143,7 +190,7 @@ func New() *Log {
 // WithOutput creates a copy of a Log with a different output.
 func (l *Log) WithOutput(w io.Writer) *Log {
 	n := l.With(Fields{})
-	n.writer = w
+	n.output = newOutput(w)
 	return n
 }
 
@@ -167,49 +214,275 @@ func (l *Log) WithTrace(traceID string, spanID string, sampled bool, projectName
 	return n
 }
 
-// AddCallerSkip increases the number of callers skipped by caller annotation.
+// WithSampler creates a copy of a Log that consults s before writing an entry
+func (l *Log) WithSampler(s *Sampler) *Log {
+	n := l.With(Fields{})
+	n.sampler = s
+	return n
+}
+
+// WithDeduplicator creates a copy of a Log that suppresses repeated error entries through d
+func (l *Log) WithDeduplicator(d *Deduplicator) *Log {
+	n := l.With(Fields{})
+	n.deduplicator = d
+	return n
+}
+
+// WithHook creates a copy of a Log with an additional hook, fired after the ones already registered
+func (l *Log) WithHook(h Hook) *Log {
+	n := l.With(Fields{})
+	n.hooks = append(n.hooks[:len(n.hooks):len(n.hooks)], h)
+	return n
+}
+
+// WithRedactor creates a copy of a Log that redacts the entry fields through r before writing them
+func (l *Log) WithRedactor(r *Redactor) *Log {
+	n := l.With(Fields{})
+	n.redactor = r
+	return n
+}
+
+// WithMetrics creates a copy of a Log that reports its activity to m
+func (l *Log) WithMetrics(m Metrics) *Log {
+	n := l.With(Fields{})
+	n.metrics = m
+	return n
+}
+
+// AddCallerSkip creates a copy of a Log which skips skip more callers in caller annotation.
 // When building wrappers around the Logger, supplying this value prevents logger
 // from always reporting the wrapper code as the caller.
-func (l *Log) AddCallerSkip(skip int) {
-	l.mux.Lock()
-	defer l.mux.Unlock()
+func (l *Log) AddCallerSkip(skip int) *Log {
+	n := l.With(Fields{})
+	n.callerSkip += skip
+	return n
+}
 
-	l.callerSkip += skip
+// log prints out a message with the passed severity level. It must be called straight from the
+// exported methods for the caller to be reported correctly.
+func (l *Log) log(severity severity, message string) {
+	l.logEntry(1, &Entry{
+		Severity: severity,
+		Message:  message,
+	})
 }
 
-func (l *Log) log(severity, message, stacktrace string, reportLocation *ReportLocation) {
-	l.mux.Lock()
-	defer l.mux.Unlock()
+// logEntry runs the entry through the sampler, the deduplicator, the hooks and the redactor,
+// then writes it. The entry time, fields, labels and caller are set here: skip is the number of
+// frames between logEntry and the exported method called by the user. The fields already set on
+// the entry are added to the ones of the Log, in its current group.
+func (l *Log) logEntry(skip int, entry *Entry) {
+	extra := entry.Fields
+
+	// With an error group, the errors are typed events without stacktrace, grouped by the key
+	if entry.Severity >= ERROR && l.errorGroup != "" {
+		entry.Reported = true
+	}
+	reported := entry.Severity >= ERROR || entry.Reported
+	if reported || l.sourceLocation {
+		fpc, file, line, _ := runtime.Caller(l.callerSkip + skip)
+		file = l.trimPath(file)
+
+		funcName := "unknown"
+		fun := runtime.FuncForPC(fpc)
+		if fun != nil {
+			_, funcName = filepath.Split(fun.Name())
+		}
+
+		if entry.Severity >= ERROR && !l.noStacktrace && l.errorGroup == "" {
+			buffer := make([]byte, 1024)
+			entry.Stacktrace = l.trimPaths(string(buffer[:runtime.Stack(buffer, false)]))
+		}
+		if reported {
+			entry.ReportLocation = &ReportLocation{
+				FilePath:     file,
+				FunctionName: funcName,
+				LineNumber:   line,
+			}
+		}
+
+		if l.sourceLocation {
+			entry.SourceLocation = &SourceLocation{
+				File:     file,
+				Line:     strconv.Itoa(line),
+				Function: funcName,
+			}
+		}
+	}
+
+	severity := entry.Severity
+
+	// Metric entries are never sampled, dropping them would skew the metrics
+	if l.sampler != nil && entry.Metric == nil {
+		ok, dropped := l.sampler.check(severity, entry.Message)
+		l.reportDropped(dropped)
+		if !ok {
+			l.dropped(severity, DropSampled)
+			return
+		}
+	}
+
+	entry.Time = l.clock.Now()
+	entry.Fields = l.fields.all()
+	entry.Labels = l.labels
+	if entry.Operation == nil {
+		entry.Operation = l.operation
+	}
+
+	repeats, ok := 0, true
+	if l.deduplicator != nil {
+		if entry.ReportLocation != nil {
+			ok, repeats = l.deduplicator.check(severity, entry.Message, entry.ReportLocation)
+		}
+		// Report the windows which are over, the one of this entry has just been claimed
+		l.reportRepeats()
+	}
+	if !ok {
+		l.dropped(severity, DropDeduplicated)
+		return
+	}
+
+	// Make sure the fields and labels of the Log are left untouched when the entry gets its own ones
+	switch {
+	case len(extra) > 0:
+		entry.Fields = l.getFields()
+		mergeAt(entry.Fields, l.group, extra)
+		if l.fields.lazy || isLazy(extra) {
+			entry.Fields = resolveLazy(entry.Fields)
+		}
+	case l.fields.lazy:
+		entry.Fields = resolveLazy(entry.Fields)
+	case repeats > 0 && len(l.hooks) == 0:
+		entry.Fields = l.getFields()
+	}
+	// Hooks can change the nested fields as well, e.g. the ones of a group
+	if len(l.hooks) > 0 {
+		entry.Fields = copyFields(entry.Fields)
+		entry.Labels = copyLabels(l.labels)
+	}
+	if repeats > 0 {
+		entry.Fields[repeatCountField] = repeats
+	}
+
+	if !l.fireHooks(entry) {
+		l.dropped(severity, DropHook)
+		return
+	}
+
+	if entry.Reported && l.errorGroup != "" {
+		l.groupError(entry)
+	}
+
+	entry.Fields = normalizeFields(entry.Fields, false)
+
+	// Redact last, so that the fields added by the hooks are redacted as well
+	if l.redactor != nil {
+		entry.Fields = l.redactor.redact(entry.Fields)
+	}
+
+	l.limitEntry(entry)
 
 	// Do not persist the payload here, just format it, marshal it and return it
-	payload := &Payload{
-		Severity:       severity,
-		EventTime:      time.Now().Format(time.RFC3339),
-		Message:        message,
+	eventTime, timestamp := l.formatTime(entry.Time)
+	l.write(entry.Severity, &Payload{
+		Severity:       entry.Severity.String(),
+		Type:           reportedType(entry),
+		EventTime:      eventTime,
+		Timestamp:      timestamp,
+		Message:        entry.Message,
 		ServiceContext: l.serviceContext,
 		Context: &Context{
-			Data:           l.fields,
-			ReportLocation: reportLocation,
+			Data:           entry.Fields,
+			ReportLocation: entry.ReportLocation,
+			User:           l.reportedUser(entry),
+			HTTPRequest:    l.reportedHTTPRequest(),
 		},
-		Stacktrace: stacktrace,
-		trace:      l.trace,
+		Metric:         entry.Metric,
+		Stacktrace:     entry.Stacktrace,
+		InsertID:       l.insertID(),
+		Labels:         entry.Labels,
+		Operation:      entry.Operation,
+		SourceLocation: entry.SourceLocation,
+		trace:          l.trace,
+	})
+}
+
+// reportRepeats writes an entry for every deduplication window which is over, with the number
+// of entries it suppressed
+func (l *Log) reportRepeats() {
+	for _, r := range l.deduplicator.sweep() {
+		location := r.ReportLocation
+		eventTime, timestamp := l.formatTime(l.clock.Now())
+		l.write(r.severity, &Payload{
+			Severity:       r.severity.String(),
+			EventTime:      eventTime,
+			Timestamp:      timestamp,
+			Message:        r.message,
+			ServiceContext: l.serviceContext,
+			Context: &Context{
+				Data:           Fields{repeatCountField: r.suppressed},
+				ReportLocation: &location,
+			},
+			InsertID: l.insertID(),
+			Labels:   l.labels,
+		})
 	}
+}
 
+// write marshals the payload, then writes it to the output and to the sinks
+func (l *Log) write(severity severity, payload *Payload) {
+	start := time.Now()
 	b, err := json.Marshal(payload)
+	if err != nil && payload.Context != nil && payload.Context.Data != nil {
+		// Replace the values which cannot be marshaled rather than dropping the whole entry, and
+		// redact their replacement
+		payload.Context.Data = normalizeFields(payload.Context.Data, true)
+		if l.redactor != nil {
+			payload.Context.Data = l.redactor.redact(payload.Context.Data)
+		}
+		b, err = json.Marshal(payload)
+	}
+	if err == nil && l.limits.Entry > 0 && len(b) > l.limits.Entry {
+		b, err = l.shrink(payload, b)
+	}
+	if l.metrics != nil {
+		l.metrics.EntryEncoded(time.Since(start))
+	}
 	if err != nil {
-		fmt.Printf("logger ERROR: cannot marshal payload: %s", err)
+		fmt.Printf("logger ERROR: cannot marshal payload: %s\n", err)
+		l.dropped(severity, DropEncodeError)
 		return
 	}
 
-	l.writer.Write(b)
-	l.writer.Write([]byte{'\n'})
+	b = append(b, '\n')
+
+	l.output.mux.Lock()
+	defer l.output.mux.Unlock()
+
+	n, err := l.output.w.Write(b)
+	if l.metrics != nil {
+		if err != nil {
+			l.metrics.WriteFailed(severity)
+		} else {
+			l.metrics.EntryWritten(severity, n)
+		}
+	}
+
+	for _, s := range l.sinks {
+		if err := s.Write(payload); err != nil {
+			fmt.Printf("logger ERROR: sink failed: %s\n", err)
+		}
+	}
+}
+
+func (l *Log) dropped(severity severity, reason DropReason) {
+	if l.metrics != nil {
+		l.metrics.EntryDropped(severity, reason)
+	}
 }
 
 // Checks whether the specified log level is valid
 func (l *Log) isValidLogLevel(s severity) bool {
-	l.mux.Lock()
-	defer l.mux.Unlock()
-
 	return s >= l.level
 }
 
@@ -217,7 +490,7 @@ func (l *Log) isValidLogLevel(s severity) bool {
 func (l *Log) getFields() Fields {
 	f := Fields{}
 
-	for k, v := range l.fields {
+	for k, v := range l.fields.all() {
 		f[k] = v
 	}
 
@@ -226,23 +499,13 @@ func (l *Log) getFields() Fields {
 
 // With is used as a chained method to specify which values go in the log entry's context
 func (l *Log) With(fields Fields) *Log {
-	l.mux.RLock()
-	defer l.mux.RUnlock()
-
-	f := l.getFields()
-
-	for k, v := range fields {
-		f[k] = v
+	// A Log holds no lock, the copy shares the output and its lock with l
+	n := *l
+	if len(fields) > 0 {
+		n.fields = newFieldSet(l.fields, l.group, fields)
 	}
 
-	return &Log{
-		serviceContext: l.serviceContext,
-		fields:         f,
-		writer:         l.writer,
-		level:          l.level,
-		callerSkip:     l.callerSkip,
-		trace:          l.trace,
-	}
+	return &n
 }
 
 // Debug prints out a message with DEBUG severity level
@@ -251,12 +514,16 @@ func (l *Log) Debug(message string) {
 		return
 	}
 
-	l.log(DEBUG.String(), message, "", nil)
+	l.log(DEBUG, message)
 }
 
 // Debugf prints out a message with DEBUG severity level
 func (l *Log) Debugf(message string, args ...interface{}) {
-	l.Debug(fmt.Sprintf(message, args...))
+	if !l.isValidLogLevel(DEBUG) {
+		return
+	}
+
+	l.log(DEBUG, fmt.Sprintf(message, args...))
 }
 
 // Info prints out a message with INFO severity level
@@ -265,12 +532,16 @@ func (l *Log) Info(message string) {
 		return
 	}
 
-	l.log(INFO.String(), message, "", nil)
+	l.log(INFO, message)
 }
 
 // Infof prints out a message with INFO severity level
 func (l *Log) Infof(message string, args ...interface{}) {
-	l.Info(fmt.Sprintf(message, args...))
+	if !l.isValidLogLevel(INFO) {
+		return
+	}
+
+	l.log(INFO, fmt.Sprintf(message, args...))
 }
 
 // Warn prints out a message with WARN severity level
@@ -279,53 +550,38 @@ func (l *Log) Warn(message string) {
 		return
 	}
 
-	l.log(WARN.String(), message, "", nil)
+	l.log(WARN, message)
 }
 
 // Warnf prints out a message with WARN severity level
 func (l *Log) Warnf(message string, args ...interface{}) {
-	l.Warn(fmt.Sprintf(message, args...))
+	if !l.isValidLogLevel(WARN) {
+		return
+	}
+
+	l.log(WARN, fmt.Sprintf(message, args...))
 }
 
 // Error prints out a message with ERROR severity level
 func (l *Log) Error(message string) {
-	l.error(ERROR.String(), message)
+	l.log(ERROR, message)
 }
 
 // Errorf prints out a message with ERROR severity level
 func (l *Log) Errorf(message string, args ...interface{}) {
-	l.error(ERROR.String(), fmt.Sprintf(message, args...))
+	l.log(ERROR, fmt.Sprintf(message, args...))
 }
 
 // Fatal is equivalent to Error() followed by a call to os.Exit(1).
 // It prints out a message with CRITICAL severity level
 func (l *Log) Fatal(message string) {
-	l.error(CRITICAL.String(), message)
+	l.log(CRITICAL, message)
 	os.Exit(1)
 }
 
 // Fatalf is equivalent to Errorf() followed by a call to os.Exit(1).
 // It prints out a message with CRITICAL severity level
 func (l *Log) Fatalf(message string, args ...interface{}) {
-	l.error(CRITICAL.String(), fmt.Sprintf(message, args...))
+	l.log(CRITICAL, fmt.Sprintf(message, args...))
 	os.Exit(1)
 }
-
-// ERROR prints out a message with the passed severity level (ERROR or CRITICAL)
-func (l *Log) error(severity, message string) {
-	buffer := make([]byte, 1024)
-	buffer = buffer[:runtime.Stack(buffer, false)]
-	fpc, file, line, _ := runtime.Caller(l.callerSkip)
-
-	funcName := "unknown"
-	fun := runtime.FuncForPC(fpc)
-	if fun != nil {
-		_, funcName = filepath.Split(fun.Name())
-	}
-
-	l.log(severity, message, string(buffer), &ReportLocation{
-		FilePath:     file,
-		FunctionName: funcName,
-		LineNumber:   line,
-	})
-}
@@ -4,23 +4,28 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
 
+// testClock freezes the time of the entries, with sub-second precision
+var testClock = NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC))
+
 func TestLoggerInfoWithOneTimeContext(t *testing.T) {
 	initConfig(DEBUG, "my-app", "1.0")
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerDebug",
 	}).WithOutput(buf)
 
 	log.Info("INFO message")
-	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -30,7 +35,7 @@ func TestLoggerInfoWithOneTimeContext(t *testing.T) {
 	buf.Reset()
 
 	log.With(Fields{"foo": "bar"}).WithOutput(buf).Info("unique INFO message")
-	expected = fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"unique INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"foo":"bar","function":"TestLoggerDebug","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected = fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"unique INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"foo":"bar","function":"TestLoggerDebug","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output file %s does not match expected string %s", got, expected)
@@ -40,7 +45,7 @@ func TestLoggerInfoWithOneTimeContext(t *testing.T) {
 	buf.Reset()
 
 	log.WithOutput(buf).Info("unique INFO message")
-	expected = fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"unique INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected = fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"unique INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -52,13 +57,13 @@ func TestLoggerErrorWithOneTimeContext(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerError",
 	}).WithOutput(buf)
 
 	log.Error("ERROR message")
-	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not contain substring %s", got, expected)
@@ -78,7 +83,7 @@ func TestLoggerErrorWithOneTimeContext(t *testing.T) {
 	buf.Reset()
 
 	log.With(Fields{"foo": "bar"}).WithOutput(buf).Error("unique ERROR message")
-	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"unique ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"foo":"bar","function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"unique ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"foo":"bar","function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not contain substring %s", got, expected)
@@ -98,7 +103,7 @@ func TestLoggerErrorWithOneTimeContext(t *testing.T) {
 	buf.Reset()
 
 	log.WithOutput(buf).Error("unique ERROR message")
-	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"unique ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"unique ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not contain substring %s", got, expected)
@@ -120,7 +125,7 @@ func TestLoggerWithDifferentLogLevels(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key": "value",
 	}).WithOutput(buf)
 
@@ -141,7 +146,7 @@ func TestLoggerWithDifferentLogLevels(t *testing.T) {
 	}
 
 	log.Warn("WARN message")
-	expected := fmt.Sprintf(`{"severity":"WARN","eventTime":"%s","message":"WARN message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"WARN","eventTime":"%s","message":"WARN message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -151,7 +156,7 @@ func TestLoggerWithDifferentLogLevels(t *testing.T) {
 	buf.Reset()
 
 	log.Error("ERROR message")
-	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected = fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got = strings.TrimRight(buf.String(), "\n")
 	if strings.Contains(got, expected) {
 		t.Errorf("expecting %s; got %s", expected, got)
@@ -163,14 +168,14 @@ func TestLoggerDebugWithImplicitContext(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerDebug",
 	}).WithOutput(buf)
 
 	log.Debug("DEBUG message")
 
-	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerDebug","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -181,10 +186,10 @@ func TestLoggerDebugWithoutContext(t *testing.T) {
 	initConfig(DEBUG, "my-app", "1.0")
 
 	buf := new(bytes.Buffer)
-	log := New().WithOutput(buf)
+	log := New().WithClock(testClock).WithOutput(buf)
 
 	log.Debug("DEBUG message")
-	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message","serviceContext":{"service":"my-app","version":"1.0"},"context":{}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message","serviceContext":{"service":"my-app","version":"1.0"},"context":{}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -196,11 +201,11 @@ func TestLoggerDebugfWithoutContext(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().WithOutput(buf)
+	log := New().WithClock(testClock).WithOutput(buf)
 
 	param := "with param"
 	log.Debugf("DEBUG message %s", param)
-	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"DEBUG","eventTime":"%s","message":"DEBUG message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -212,13 +217,13 @@ func TestLoggerInfo(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerInfo",
 	}).WithOutput(buf)
 
 	log.Info("INFO message")
-	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -230,14 +235,14 @@ func TestLoggerInfof(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerInfo",
 	}).WithOutput(buf)
 
 	param := "with param"
 	log.Infof("INFO message %s", param)
-	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
@@ -248,7 +253,7 @@ func TestResponseIsValidJson(t *testing.T) {
 	initConfig(DEBUG, "my-app", "1.0")
 
 	buf := new(bytes.Buffer)
-	log := New().With(Fields{"key": "value"}).WithOutput(buf)
+	log := New().WithClock(testClock).With(Fields{"key": "value"}).WithOutput(buf)
 
 	log.Error("ERROR message")
 	got := strings.TrimRight(buf.String(), "\n")
@@ -264,7 +269,7 @@ func TestGetCallerFunctionName(t *testing.T) {
 	initConfig(DEBUG, "my-app", "1.0")
 
 	buf := new(bytes.Buffer)
-	log := New().With(Fields{"key": "value"}).WithOutput(buf)
+	log := New().WithClock(testClock).With(Fields{"key": "value"}).WithOutput(buf)
 
 	log.Error("ERROR message")
 	got := strings.TrimRight(buf.String(), "\n")
@@ -287,13 +292,13 @@ func TestLoggerError(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerError",
 	}).WithOutput(buf)
 
 	log.Error("ERROR message")
-	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not containsubstring %s", got, expected)
@@ -315,10 +320,10 @@ func TestLoggerErrorWithoutContext(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().WithOutput(buf)
+	log := New().WithClock(testClock).WithOutput(buf)
 
 	log.Error("ERROR message")
-	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not containsubstring %s", got, expected)
@@ -340,14 +345,14 @@ func TestLoggerErrorf(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"key":      "value",
 		"function": "TestLoggerError",
 	}).WithOutput(buf)
 
 	param := "with param"
 	log.Errorf("ERROR message %s", param)
-	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message with param","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerError","key":"value"},"reportLocation"`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not containsubstring %s", got, expected)
@@ -359,14 +364,14 @@ func TestLoggerInfoWithSeveralContextEntries(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"function": "TestLoggerInfo",
 		"key":      "value",
 		"package":  "logger",
 	}).WithOutput(buf)
 
 	log.Info("INFO message")
-	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value","package":"logger"}}}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%s","message":"INFO message","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestLoggerInfo","key":"value","package":"logger"}}}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output file %s does not match expected string %s", got, expected)
@@ -378,14 +383,14 @@ func TestLoggerErrorWithSeveralContextEntries(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().With(Fields{
+	log := New().WithClock(testClock).With(Fields{
 		"function": "TestLoggerError",
 		"key":      "value",
 		"package":  "logger",
 	}).WithOutput(buf)
 
 	log.Error("ERROR message")
-	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"}`, time.Now().Format(time.RFC3339))
+	expected := fmt.Sprintf(`{"severity":"ERROR","eventTime":"%s","message":"ERROR message","serviceContext":{"service":"my-app","version":"1.0"}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if !strings.Contains(got, expected) {
 		t.Errorf("output %s does not containsubstring %s", got, expected)
@@ -413,7 +418,7 @@ func TestMultipleLogLevels(t *testing.T) {
 
 	var (
 		buf        = new(bytes.Buffer)
-		defaultLog = New().WithOutput(buf)
+		defaultLog = New().WithClock(testClock).WithOutput(buf)
 		warnLog    = defaultLog.WithLevel(WARN)
 	)
 
@@ -448,14 +453,14 @@ func TestCallerSkip(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	baseLog := New().WithOutput(buf)
+	baseLog := New().WithClock(testClock).WithOutput(buf)
 	baseLog.Errorf("base log error")
 	if !strings.Contains(buf.String(), `"functionName":"logger.TestCallerSkip"`) {
 		t.Errorf("invalid function name in error log: %s", buf)
 	}
 	buf.Reset()
 
-	baseLog.AddCallerSkip(1)
+	baseLog = baseLog.AddCallerSkip(1)
 	customLog := customLog{base: baseLog}
 	func() {
 		customLog.Error("custom log error")
@@ -470,7 +475,7 @@ func TestStackTraceIssue(t *testing.T) {
 	initConfig(DEBUG, "stack-trace-issue", "1.0")
 	var (
 		buf = new(bytes.Buffer)
-		log = New().WithOutput(buf)
+		log = New().WithClock(testClock).WithOutput(buf)
 		q   = `"stacktrace":"`
 	)
 
@@ -493,7 +498,7 @@ func TestWithTrace(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
-	log := New().
+	log := New().WithClock(testClock).
 		With(Fields{
 			"function": t.Name(),
 			"key":      "value",
@@ -510,9 +515,78 @@ func TestWithTrace(t *testing.T) {
 	log.Info("test2")
 
 	expected := fmt.Sprintf(`{"severity":"INFO","eventTime":"%[1]s","message":"test1","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestWithTrace","key":"value","package":"logger"}},"logging.googleapis.com/trace":"projects/projectName/traces/traceID","logging.googleapis.com/trace_sampled":false,"logging.googleapis.com/spanId":"spanID1"}
-{"severity":"INFO","eventTime":"%[1]s","message":"test2","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestWithTrace","key":"value","package":"new"}},"logging.googleapis.com/trace":"projects/projectName/traces/traceID","logging.googleapis.com/trace_sampled":false,"logging.googleapis.com/spanId":"spanID2"}`, time.Now().Format(time.RFC3339))
+{"severity":"INFO","eventTime":"%[1]s","message":"test2","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"function":"TestWithTrace","key":"value","package":"new"}},"logging.googleapis.com/trace":"projects/projectName/traces/traceID","logging.googleapis.com/trace_sampled":false,"logging.googleapis.com/spanId":"spanID2"}`, testClock.Now().Format(time.RFC3339Nano))
 	got := strings.TrimRight(buf.String(), "\n")
 	if expected != got {
 		t.Errorf("output %s does not match expected string %s", got, expected)
 	}
 }
+
+func TestConcurrentLogging(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l := log.With(Fields{"goroutine": i}).AddCallerSkip(0)
+			for j := 0; j < 100; j++ {
+				l.Infof("message %d", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 800 {
+		t.Fatalf("expected 800 entries, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("interleaved entry %s", line)
+		}
+	}
+}
+
+func BenchmarkInfoParallel(b *testing.B) {
+	initConfig(INFO, "my-app", "1.0")
+
+	log := New().WithOutput(ioutil.Discard).With(Fields{"key": "value"})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.Info("INFO message")
+		}
+	})
+}
+
+func BenchmarkDisabledDebugParallel(b *testing.B) {
+	initConfig(INFO, "my-app", "1.0")
+
+	log := New().WithOutput(ioutil.Discard).With(Fields{"key": "value"})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.Debugf("message %d", 42)
+		}
+	})
+}
+
+func BenchmarkWithParallel(b *testing.B) {
+	initConfig(INFO, "my-app", "1.0")
+
+	log := New().WithOutput(ioutil.Discard).With(Fields{"key": "value"})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			log.With(Fields{"request": 42}).Info("INFO message")
+		}
+	})
+}
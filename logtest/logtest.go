@@ -0,0 +1,235 @@
+// Package logtest provides helpers to test the code using the logger: a Log whose entries are
+// captured by an Observer, decoded, and printed through t.Log only if the test fails.
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"bendingspoons.com/logger"
+)
+
+// Entry is an entry captured by an Observer
+type Entry struct {
+	logger.Payload
+	// Raw is the entry as written by the logger, without the trailing newline
+	Raw string
+}
+
+// Fields returns the fields of the entry, an empty Fields if it has none
+func (e Entry) Fields() logger.Fields {
+	if e.Context == nil || e.Context.Data == nil {
+		return logger.Fields{}
+	}
+
+	return e.Context.Data
+}
+
+// Entries is a list of captured entries, which can be filtered
+type Entries []Entry
+
+// FilterSeverity returns the entries with the given severity level
+func (es Entries) FilterSeverity(s logger.Severity) Entries {
+	return es.Filter(func(e Entry) bool {
+		return e.Severity == s.String()
+	})
+}
+
+// FilterMessage returns the entries with the given message
+func (es Entries) FilterMessage(msg string) Entries {
+	return es.Filter(func(e Entry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterMessageContains returns the entries whose message contains substr
+func (es Entries) FilterMessageContains(substr string) Entries {
+	return es.Filter(func(e Entry) bool {
+		return strings.Contains(e.Message, substr)
+	})
+}
+
+// FilterField returns the entries with the field key set to value. Values are compared through
+// their JSON representation, so that e.g. an int matches the decoded float64.
+func (es Entries) FilterField(key string, value interface{}) Entries {
+	expected, ok := normalize(value)
+
+	return es.Filter(func(e Entry) bool {
+		v, found := e.Fields()[key]
+		return ok && found && reflect.DeepEqual(v, expected)
+	})
+}
+
+// Filter returns the entries matching f
+func (es Entries) Filter(f func(Entry) bool) Entries {
+	var filtered Entries
+	for _, e := range es {
+		if f(e) {
+			filtered = append(filtered, e)
+		}
+	}
+
+	return filtered
+}
+
+// Observer is an io.Writer capturing and decoding the entries written by a logger.
+// It is safe for concurrent use.
+type Observer struct {
+	mux     sync.Mutex
+	entries Entries
+	errs    []error
+}
+
+// NewObserver instantiates and returns an empty Observer
+func NewObserver() *Observer {
+	return &Observer{}
+}
+
+// Write implements io.Writer, every line is decoded as an entry
+func (o *Observer) Write(p []byte) (int, error) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte{'\n'}) {
+		e := Entry{Raw: string(line)}
+		if err := json.Unmarshal(line, &e.Payload); err != nil {
+			o.errs = append(o.errs, err)
+			continue
+		}
+		o.entries = append(o.entries, e)
+	}
+
+	return len(p), nil
+}
+
+// Entries returns a copy of the entries captured so far
+func (o *Observer) Entries() Entries {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return append(Entries(nil), o.entries...)
+}
+
+// Len returns the number of entries captured so far
+func (o *Observer) Len() int {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	return len(o.entries)
+}
+
+// Reset forgets the entries captured so far
+func (o *Observer) Reset() {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	o.entries = nil
+	o.errs = nil
+}
+
+// AssertLogged fails the test unless an entry with the given severity level and message was
+// captured, and returns the first one
+func (o *Observer) AssertLogged(t testing.TB, s logger.Severity, msg string) Entry {
+	t.Helper()
+
+	es := o.Entries().FilterSeverity(s).FilterMessage(msg)
+	if len(es) == 0 {
+		t.Errorf("no %s entry with message %q was logged", s, msg)
+		return Entry{}
+	}
+
+	return es[0]
+}
+
+// AssertNotLogged fails the test if an entry with the given severity level and message was captured
+func (o *Observer) AssertNotLogged(t testing.TB, s logger.Severity, msg string) {
+	t.Helper()
+
+	if es := o.Entries().FilterSeverity(s).FilterMessage(msg); len(es) > 0 {
+		t.Errorf("%d %s entries with message %q were logged: %s", len(es), s, msg, es[0].Raw)
+	}
+}
+
+// AssertCount fails the test unless n entries were captured
+func (o *Observer) AssertCount(t testing.TB, n int) {
+	t.Helper()
+
+	if got := o.Len(); got != n {
+		t.Errorf("expected %d entries to be logged, got %d", n, got)
+	}
+}
+
+// AssertValid fails the test if some lines written to the Observer were not valid entries
+func (o *Observer) AssertValid(t testing.TB) {
+	t.Helper()
+
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	for _, err := range o.errs {
+		t.Errorf("invalid entry logged: %s", err)
+	}
+}
+
+// AssertField fails the test unless the entry has the field key set to value
+func AssertField(t testing.TB, e Entry, key string, value interface{}) {
+	t.Helper()
+
+	if len(Entries{e}.FilterField(key, value)) == 0 {
+		t.Errorf("expected field %q to be %v in entry %s", key, value, e.Raw)
+	}
+}
+
+// normalize returns v as it would be decoded from the logger output
+func normalize(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	var n interface{}
+	if err := json.Unmarshal(b, &n); err != nil {
+		return nil, false
+	}
+
+	return n, true
+}
+
+// tbWriter prints what is written to it through t.Log
+type tbWriter struct {
+	t testing.TB
+}
+
+// NewWriter returns an io.Writer printing every entry of the logger output through t.Log, which
+// go test only shows for the tests which failed, or with the -v flag
+func NewWriter(t testing.TB) io.Writer {
+	return tbWriter{t: t}
+}
+
+func (w tbWriter) Write(p []byte) (int, error) {
+	w.t.Helper()
+
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if line != "" {
+			w.t.Log(line)
+		}
+	}
+
+	return len(p), nil
+}
+
+// New returns a Log with DEBUG log level along with the Observer capturing its entries.
+// The entries are also printed through t.Log, shown by go test if the test fails.
+func New(t testing.TB) (*logger.Log, *Observer) {
+	o := NewObserver()
+	log := logger.New().
+		WithLevel(logger.DEBUG).
+		WithOutput(io.MultiWriter(o, NewWriter(t)))
+
+	return log, o
+}
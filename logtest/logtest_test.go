@@ -0,0 +1,100 @@
+package logtest
+
+import (
+	"fmt"
+	"testing"
+
+	"bendingspoons.com/logger"
+)
+
+// fakeTB records what the helpers report instead of failing the test
+type fakeTB struct {
+	testing.TB
+	failed bool
+	errors []string
+	logs   []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Log(args ...interface{}) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Failed() bool {
+	return f.failed
+}
+
+func TestObserver(t *testing.T) {
+	log, obs := New(t)
+
+	log.With(logger.Fields{"user": 42}).Debug("created")
+	log.With(logger.Fields{"user": 43}).Info("created")
+	log.Warn("slow request")
+	log.Error("failed")
+
+	obs.AssertCount(t, 4)
+	obs.AssertValid(t)
+
+	created := obs.Entries().FilterMessage("created")
+	if len(created) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(created))
+	}
+	if es := created.FilterSeverity(logger.INFO).FilterField("user", 43); len(es) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(es))
+	}
+	if es := obs.Entries().FilterMessageContains("slow"); len(es) != 1 || es[0].Severity != "WARN" {
+		t.Errorf("unexpected entries %+v", es)
+	}
+
+	e := obs.AssertLogged(t, logger.DEBUG, "created")
+	AssertField(t, e, "user", 42)
+	obs.AssertNotLogged(t, logger.ERROR, "created")
+
+	if e := obs.AssertLogged(t, logger.ERROR, "failed"); e.Context.ReportLocation == nil || len(e.Fields()) != 0 {
+		t.Errorf("unexpected error entry %s", e.Raw)
+	}
+
+	obs.Reset()
+	obs.AssertCount(t, 0)
+}
+
+func TestAssertionsFail(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	log, obs := New(tb)
+
+	log.With(logger.Fields{"user": 42}).Info("created")
+
+	e := obs.AssertLogged(tb, logger.INFO, "created")
+	AssertField(tb, e, "user", 43)
+	obs.AssertLogged(tb, logger.WARN, "created")
+	obs.AssertNotLogged(tb, logger.INFO, "created")
+	obs.AssertCount(tb, 2)
+
+	if len(tb.errors) != 4 {
+		t.Errorf("expected 4 failed assertions, got %d: %v", len(tb.errors), tb.errors)
+	}
+
+	obs.Write([]byte("not json\n"))
+	obs.AssertValid(tb)
+	if len(tb.errors) != 5 {
+		t.Errorf("expected the invalid entry to be reported, got %v", tb.errors)
+	}
+}
+
+func TestWriterLogsEntries(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	log, obs := New(tb)
+	log.Info("first")
+	log.Info("second")
+
+	entries := obs.Entries()
+	if len(tb.logs) != 2 || tb.logs[0] != entries[0].Raw || tb.logs[1] != entries[1].Raw {
+		t.Errorf("expected the entries to be printed through t.Log, got %v", tb.logs)
+	}
+}
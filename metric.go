@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Units of the metrics emitted by the Counter and Timer helpers
+const (
+	UnitCount        = "1"
+	UnitMilliseconds = "ms"
+)
+
+// Metric is the structured value of a metric entry, emitted under the "metric" key so that
+// log-based metrics can be defined on stable JSON paths, e.g. jsonPayload.metric.value
+type Metric struct {
+	Name   string            `json:"name"`
+	Value  float64           `json:"value"`
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Metric prints out a metric entry with INFO severity level, the message being the metric name
+func (l *Log) Metric(name string, value float64, labels map[string]string) {
+	l.metric(name, value, "", labels)
+}
+
+// MetricWithUnit prints out a metric entry with INFO severity level and the given unit
+func (l *Log) MetricWithUnit(name string, value float64, unit string, labels map[string]string) {
+	l.metric(name, value, unit, labels)
+}
+
+// metric must be called straight from the exported methods for the caller to be reported correctly.
+// NaN and infinite values cannot be encoded, their entries are dropped.
+func (l *Log) metric(name string, value float64, unit string, labels map[string]string) {
+	if !l.isValidLogLevel(INFO) {
+		return
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		fmt.Printf("logger ERROR: cannot log metric %s with the non-finite value %v\n", name, value)
+		l.dropped(INFO, DropEncodeError)
+		return
+	}
+
+	l.logEntry(1, &Entry{
+		Severity: INFO,
+		Message:  name,
+		Metric: &Metric{
+			Name:   name,
+			Value:  value,
+			Unit:   unit,
+			Labels: copyLabels(labels),
+		},
+	})
+}
+
+// Counter is a helper emitting counter metric entries
+type Counter struct {
+	log    *Log
+	name   string
+	labels map[string]string
+}
+
+// Counter returns a Counter emitting metric entries with the given name and labels
+func (l *Log) Counter(name string, labels map[string]string) *Counter {
+	return &Counter{
+		log:    l,
+		name:   name,
+		labels: labels,
+	}
+}
+
+// Inc prints out a metric entry counting one occurrence
+func (c *Counter) Inc() {
+	c.log.metric(c.name, 1, UnitCount, c.labels)
+}
+
+// Add prints out a metric entry counting delta occurrences
+func (c *Counter) Add(delta float64) {
+	c.log.metric(c.name, delta, UnitCount, c.labels)
+}
+
+// Timer is a helper emitting the duration of an operation as a metric entry
+type Timer struct {
+	log    *Log
+	name   string
+	labels map[string]string
+	start  time.Time
+}
+
+// Timer starts and returns a Timer emitting a metric entry with the given name once stopped
+func (l *Log) Timer(name string) *Timer {
+	return &Timer{
+		log:   l,
+		name:  name,
+		start: l.clock.Now(),
+	}
+}
+
+// WithLabels sets the labels of the metric entry emitted by the Timer
+func (t *Timer) WithLabels(labels map[string]string) *Timer {
+	t.labels = labels
+	return t
+}
+
+// Stop prints out a metric entry with the time elapsed since the Timer was started,
+// in milliseconds, and returns it
+func (t *Timer) Stop() time.Duration {
+	d := t.log.clock.Now().Sub(t.start)
+	t.log.metric(t.name, float64(d)/float64(time.Millisecond), UnitMilliseconds, t.labels)
+	return d
+}
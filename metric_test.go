@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetric(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().With(Fields{"key": "value"}).WithOutput(buf)
+
+	log.Metric("CUSTOM_METRIC", 42, map[string]string{"region": "eu"})
+	expected := `"message":"CUSTOM_METRIC","serviceContext":{"service":"my-app","version":"1.0"},"context":{"data":{"key":"value"}},"metric":{"name":"CUSTOM_METRIC","value":42,"labels":{"region":"eu"}}}`
+	got := strings.TrimRight(buf.String(), "\n")
+	if !strings.HasPrefix(got, `{"severity":"INFO"`) || !strings.HasSuffix(got, expected) {
+		t.Errorf("output %s does not match expected string %s", got, expected)
+	}
+}
+
+func TestMetricIsNotSampled(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(NewSampler(time.Minute, 1, 0))
+
+	c := log.Counter("requests", nil)
+	for i := 0; i < 3; i++ {
+		c.Inc()
+	}
+
+	if n := strings.Count(buf.String(), `"metric":{"name":"requests","value":1,"unit":"1"}`); n != 3 {
+		t.Errorf("expected 3 metric entries, got %d: %s", n, buf)
+	}
+}
+
+func TestMetricRespectsLogLevel(t *testing.T) {
+	initConfig(WARN, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	New().WithOutput(buf).Metric("CUSTOM_METRIC", 1, nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("output %s should be empty", buf)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf)
+
+	timer := log.Timer("db_query").WithLabels(map[string]string{"table": "users"})
+	time.Sleep(time.Millisecond)
+	d := timer.Stop()
+
+	p := Payload{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+
+	if p.Metric == nil {
+		t.Fatalf("output %s does not contain a metric", buf)
+	}
+	if p.Metric.Name != "db_query" || p.Metric.Unit != UnitMilliseconds || p.Metric.Labels["table"] != "users" {
+		t.Errorf("unexpected metric %+v", p.Metric)
+	}
+	if p.Metric.Value != float64(d)/float64(time.Millisecond) || p.Metric.Value < 1 {
+		t.Errorf("unexpected metric value %v for a duration of %s", p.Metric.Value, d)
+	}
+}
+
+func TestMetricRejectsNonFiniteValues(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	stats := NewStats()
+	log := New().WithOutput(buf).WithMetrics(stats)
+
+	log.Metric("CUSTOM_METRIC", math.NaN(), nil)
+	log.Counter("CUSTOM_COUNTER", nil).Add(math.Inf(1))
+
+	if buf.Len() != 0 {
+		t.Errorf("expected the metrics to be rejected, got %s", buf)
+	}
+	if n := stats.Snapshot().Dropped[DropEncodeError.String()]; n != 2 {
+		t.Errorf("expected 2 entries dropped, got %d", n)
+	}
+}
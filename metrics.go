@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// DropReason tells why an entry was not written
+type DropReason int
+
+const (
+	// DropSampled is used for the entries dropped by a Sampler
+	DropSampled DropReason = iota
+	// DropDeduplicated is used for the entries suppressed by a Deduplicator
+	DropDeduplicated
+	// DropHook is used for the entries vetoed by a Hook
+	DropHook
+	// DropEncodeError is used for the entries which could not be marshalled
+	DropEncodeError
+)
+
+var dropReasonName = [...]string{
+	"sampled",
+	"deduplicated",
+	"hook",
+	"encode_error",
+}
+
+func (r DropReason) String() string {
+	return dropReasonName[r]
+}
+
+// Metrics receives the activity of the loggers it is registered on.
+// It is meant to be implemented on top of a metrics library, e.g. with Prometheus counters,
+// so that the logger itself does not depend on any. Methods can be called concurrently.
+type Metrics interface {
+	// EntryWritten is called for every entry written, with the number of bytes written
+	EntryWritten(s Severity, bytes int)
+	// WriteFailed is called for every entry the output failed to write
+	WriteFailed(s Severity)
+	// EntryDropped is called for every entry dropped before being written
+	EntryDropped(s Severity, reason DropReason)
+	// EntryEncoded is called with the time spent marshalling every entry
+	EntryEncoded(d time.Duration)
+}
+
+// Stats is a Metrics implementation keeping plain counters in memory.
+// It implements expvar.Var, so it can be published as is with expvar.Publish.
+type Stats struct {
+	entries     [len(logLevelName)]int64
+	bytes       int64
+	writeErrors int64
+	dropped     [len(dropReasonName)]int64
+	encodes     int64
+	encodeNanos int64
+}
+
+// StatsSnapshot holds the values of the Stats counters at a given time
+type StatsSnapshot struct {
+	Entries       map[string]int64 `json:"entries"`
+	Bytes         int64            `json:"bytes"`
+	WriteErrors   int64            `json:"writeErrors"`
+	Dropped       map[string]int64 `json:"dropped"`
+	Encodes       int64            `json:"encodes"`
+	EncodeSeconds float64          `json:"encodeSeconds"`
+}
+
+// NewStats instantiates and returns an empty Stats
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+// EntryWritten implements Metrics
+func (s *Stats) EntryWritten(sev Severity, bytes int) {
+	atomic.AddInt64(&s.entries[sev], 1)
+	atomic.AddInt64(&s.bytes, int64(bytes))
+}
+
+// WriteFailed implements Metrics
+func (s *Stats) WriteFailed(Severity) {
+	atomic.AddInt64(&s.writeErrors, 1)
+}
+
+// EntryDropped implements Metrics
+func (s *Stats) EntryDropped(_ Severity, reason DropReason) {
+	atomic.AddInt64(&s.dropped[reason], 1)
+}
+
+// EntryEncoded implements Metrics
+func (s *Stats) EntryEncoded(d time.Duration) {
+	atomic.AddInt64(&s.encodes, 1)
+	atomic.AddInt64(&s.encodeNanos, int64(d))
+}
+
+// Snapshot returns the current values of the counters
+func (s *Stats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		Entries:       map[string]int64{},
+		Bytes:         atomic.LoadInt64(&s.bytes),
+		WriteErrors:   atomic.LoadInt64(&s.writeErrors),
+		Dropped:       map[string]int64{},
+		Encodes:       atomic.LoadInt64(&s.encodes),
+		EncodeSeconds: time.Duration(atomic.LoadInt64(&s.encodeNanos)).Seconds(),
+	}
+
+	for i := range s.entries {
+		snap.Entries[severity(i).String()] = atomic.LoadInt64(&s.entries[i])
+	}
+	for i := range s.dropped {
+		snap.Dropped[DropReason(i).String()] = atomic.LoadInt64(&s.dropped[i])
+	}
+
+	return snap
+}
+
+// String returns the snapshot of the counters as JSON, as expected by expvar.Var
+func (s *Stats) String() string {
+	b, _ := json.Marshal(s.Snapshot())
+	return string(b)
+}
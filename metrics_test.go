@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestStats(t *testing.T) {
+	initConfig(INFO, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	stats := NewStats()
+	veto := &testHook{
+		levels: []Severity{WARN},
+		fire:   func(*Entry) error { return ErrDropEntry },
+	}
+
+	log := New().WithOutput(buf).WithMetrics(stats).
+		WithSampler(NewSampler(time.Minute, 1, 0)).
+		WithHook(veto)
+
+	log.Debug("below the log level")
+	log.Info("INFO message")
+	log.Info("INFO message")
+	log.Warn("WARN message")
+	log.Error("ERROR message")
+	log.WithOutput(failingWriter{}).Error("ERROR message")
+
+	snap := stats.Snapshot()
+	if snap.Entries["DEBUG"] != 0 || snap.Entries["INFO"] != 1 || snap.Entries["WARN"] != 0 || snap.Entries["ERROR"] != 1 {
+		t.Errorf("unexpected entries %v", snap.Entries)
+	}
+	if snap.Bytes != int64(buf.Len()) {
+		t.Errorf("expected %d bytes, got %d", buf.Len(), snap.Bytes)
+	}
+	if snap.WriteErrors != 1 {
+		t.Errorf("expected 1 write error, got %d", snap.WriteErrors)
+	}
+	if snap.Dropped["sampled"] != 1 || snap.Dropped["hook"] != 1 {
+		t.Errorf("unexpected dropped entries %v", snap.Dropped)
+	}
+	if snap.Encodes != 3 {
+		t.Errorf("expected 3 encoded entries, got %d", snap.Encodes)
+	}
+
+	// Stats can be published through expvar
+	v := StatsSnapshot{}
+	if err := json.Unmarshal([]byte(stats.String()), &v); err != nil {
+		t.Errorf("failed to unmarshal stats: %s", err)
+	}
+	if v.Entries["INFO"] != 1 {
+		t.Errorf("unexpected stats %s", stats)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	parentOperationField = "parentOperationId"
+	durationField        = "durationMs"
+	outcomeField         = "outcome"
+	errorField           = "error"
+
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+// OperationLog is a Log scoped to an operation started with StartOperation.
+// Every entry it prints out belongs to the operation, and so do the ones of the child operations
+// started from it, which also reference it through a parentOperationId field.
+type OperationLog struct {
+	*Log
+
+	name  string
+	start time.Time
+	ended int32
+}
+
+// StartOperation prints out an INFO entry marking the start of the operation name and returns an
+// OperationLog, whose End method must be called once the operation is over
+func (l *Log) StartOperation(name string) *OperationLog {
+	fields := Fields{}
+	if l.operation != nil {
+		fields[parentOperationField] = l.operation.ID
+	}
+
+	o := &OperationLog{
+		Log:   l.withRootFields(fields).WithOperation(newID(), name),
+		name:  name,
+		start: l.clock.Now(),
+	}
+
+	if o.isValidLogLevel(INFO) {
+		o.logEntry(0, &Entry{
+			Severity:  INFO,
+			Message:   fmt.Sprintf("%s started", name),
+			Operation: &Operation{ID: o.operation.ID, Producer: name, First: true},
+		})
+	}
+
+	return o
+}
+
+// End prints out an entry marking the end of the operation, with its duration and outcome.
+// The entry has INFO severity level if err is nil, ERROR otherwise. Only the first call has effect.
+func (o *OperationLog) End(err error) {
+	if !atomic.CompareAndSwapInt32(&o.ended, 0, 1) {
+		return
+	}
+
+	d := o.clock.Now().Sub(o.start)
+	fields := Fields{
+		durationField: float64(d) / float64(time.Millisecond),
+		outcomeField:  outcomeSuccess,
+	}
+
+	severity := INFO
+	message := fmt.Sprintf("%s finished in %s", o.name, d)
+	if err != nil {
+		severity = ERROR
+		message = fmt.Sprintf("%s failed after %s: %s", o.name, d, err)
+		fields[outcomeField] = outcomeFailure
+		fields[errorField] = err.Error()
+	}
+
+	if severity < ERROR && !o.isValidLogLevel(severity) {
+		return
+	}
+
+	o.withRootFields(fields).logEntry(0, &Entry{
+		Severity:  severity,
+		Message:   message,
+		Operation: &Operation{ID: o.operation.ID, Producer: o.name, Last: true},
+	})
+}
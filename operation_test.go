@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decodePayloads(t *testing.T, buf *bytes.Buffer) []Payload {
+	t.Helper()
+
+	var payloads []Payload
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		p := Payload{}
+		if err := json.Unmarshal([]byte(line), &p); err != nil {
+			t.Fatalf("failed to unmarshal payload %s: %s", line, err)
+		}
+		payloads = append(payloads, p)
+	}
+
+	return payloads
+}
+
+func TestOperation(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().With(Fields{"key": "value"}).WithOutput(buf)
+
+	op := log.StartOperation("import")
+	op.Info("step")
+	child := op.StartOperation("download")
+	child.End(nil)
+	op.End(errors.New("disk full"))
+	op.End(nil)
+
+	p := decodePayloads(t, buf)
+	if len(p) != 5 {
+		t.Fatalf("expected 5 entries, got %d: %s", len(p), buf)
+	}
+
+	id := p[0].Operation.ID
+	if id == "" || *p[0].Operation != (Operation{ID: id, Producer: "import", First: true}) {
+		t.Errorf("unexpected start operation %+v", p[0].Operation)
+	}
+	if p[0].Message != "import started" || p[0].Context.Data["key"] != "value" {
+		t.Errorf("unexpected start entry %+v", p[0])
+	}
+
+	if *p[1].Operation != (Operation{ID: id, Producer: "import"}) {
+		t.Errorf("unexpected operation %+v", p[1].Operation)
+	}
+
+	childID := p[2].Operation.ID
+	if childID == id || !p[2].Operation.First || p[2].Context.Data[parentOperationField] != id {
+		t.Errorf("unexpected child start entry %+v", p[2])
+	}
+	if *p[3].Operation != (Operation{ID: childID, Producer: "download", Last: true}) || p[3].Context.Data[outcomeField] != outcomeSuccess {
+		t.Errorf("unexpected child end entry %+v", p[3])
+	}
+	if _, ok := p[3].Context.Data[durationField].(float64); !ok || p[3].Severity != "INFO" {
+		t.Errorf("expected an INFO entry with a duration, got %+v", p[3])
+	}
+
+	if *p[4].Operation != (Operation{ID: id, Producer: "import", Last: true}) {
+		t.Errorf("unexpected end operation %+v", p[4].Operation)
+	}
+	if p[4].Severity != "ERROR" || p[4].Context.Data[outcomeField] != outcomeFailure || p[4].Context.Data[errorField] != "disk full" {
+		t.Errorf("unexpected end entry %+v", p[4])
+	}
+	if p[4].Context.ReportLocation.FunctionName != "logger.TestOperation" {
+		t.Errorf("unexpected report location %+v", p[4].Context.ReportLocation)
+	}
+	if _, ok := p[4].Context.Data[parentOperationField]; ok {
+		t.Errorf("root operation should not have a parent: %+v", p[4])
+	}
+}
+
+func TestOperationFieldsIgnoreGroups(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).WithStacktrace(false).Named("jobs")
+
+	parent := log.StartOperation("backfill")
+	child := parent.StartOperation("batch")
+	child.With(Fields{"batch": 1}).Info("INFO message")
+	child.End(errors.New("timeout"))
+
+	payloads := decodePayloads(t, buf)
+	if len(payloads) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(payloads))
+	}
+
+	info := payloads[2].Context.Data
+	if info[parentOperationField] != parent.operation.ID || info["jobs"].(map[string]interface{})["batch"] != float64(1) {
+		t.Errorf("unexpected fields %v", info)
+	}
+
+	end := payloads[3].Context.Data
+	if end[parentOperationField] != parent.operation.ID || end[outcomeField] != outcomeFailure ||
+		end[errorField] != "timeout" || end[durationField] != float64(0) {
+		t.Errorf("expected the operation fields at the root, got %v", end)
+	}
+	if _, ok := end["jobs"]; ok {
+		t.Errorf("expected no group, got %v", end)
+	}
+}
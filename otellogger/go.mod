@@ -0,0 +1,26 @@
+module bendingspoons.com/logger/otellogger
+
+// The OpenTelemetry SDK requires Go 1.26, unlike the logger module which only requires Go 1.12
+go 1.26.0
+
+require (
+	bendingspoons.com/logger v1.1.0
+	go.opentelemetry.io/otel v1.47.0
+	go.opentelemetry.io/otel/sdk v1.47.0
+	go.opentelemetry.io/otel/trace v1.47.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/go-logr/logr v1.4.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
+	go.opentelemetry.io/otel/log v1.47.0 // indirect
+	go.opentelemetry.io/otel/metric v1.47.0 // indirect
+	golang.org/x/sys v0.48.0 // indirect
+)
+
+// Build against the logger module of the same checkout when developing in this repository,
+// the dependents of this module use the release required above, which must be deployed first
+replace bendingspoons.com/logger => ../
@@ -0,0 +1,87 @@
+// Package otellogger bridges the OpenTelemetry trace context into the logger, so that entries
+// are correlated with the active span in Cloud Logging. It lives in its own module to keep the
+// OpenTelemetry dependency out of the logger package.
+package otellogger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"bendingspoons.com/logger"
+)
+
+// Attributes of the span events recorded for the error entries
+const (
+	eventName           = "log"
+	severityAttribute   = "log.severity"
+	messageAttribute    = "log.message"
+	functionAttribute   = "code.function"
+	filePathAttribute   = "code.filepath"
+	lineNumberAttribute = "code.lineno"
+)
+
+// Option configures how the span is bridged into the logger
+type Option func(*options)
+
+type options struct {
+	recordErrors bool
+}
+
+// RecordErrors records the ERROR and CRITICAL entries as events of the span
+func RecordErrors() Option {
+	return func(o *options) {
+		o.recordErrors = true
+	}
+}
+
+// WithContext creates a copy of l with the trace, spanId and trace_sampled fields of the span
+// active in ctx, in Cloud Logging format. projectID is the GCP project the traces belong to.
+// l is returned as is if ctx holds no valid span.
+func WithContext(ctx context.Context, l *logger.Log, projectID string, opts ...Option) *logger.Log {
+	span := trace.SpanFromContext(ctx)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return l
+	}
+
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l = l.WithTrace(sc.TraceID().String(), sc.SpanID().String(), sc.IsSampled(), projectID)
+	if o.recordErrors {
+		l = l.WithHook(&spanHook{span: span})
+	}
+
+	return l
+}
+
+// spanHook records the entries it is fired for as events of the span
+type spanHook struct {
+	span trace.Span
+}
+
+func (h *spanHook) Levels() []logger.Severity {
+	return []logger.Severity{logger.ERROR, logger.CRITICAL}
+}
+
+func (h *spanHook) Fire(e *logger.Entry) error {
+	attrs := []attribute.KeyValue{
+		attribute.String(severityAttribute, e.Severity.String()),
+		attribute.String(messageAttribute, e.Message),
+	}
+
+	if loc := e.ReportLocation; loc != nil {
+		attrs = append(attrs,
+			attribute.String(functionAttribute, loc.FunctionName),
+			attribute.String(filePathAttribute, loc.FilePath),
+			attribute.Int(lineNumberAttribute, loc.LineNumber),
+		)
+	}
+
+	h.span.AddEvent(eventName, trace.WithAttributes(attrs...), trace.WithTimestamp(e.Time))
+	return nil
+}
@@ -0,0 +1,61 @@
+package otellogger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"bendingspoons.com/logger"
+)
+
+func TestWithContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "operation")
+
+	buf := new(bytes.Buffer)
+	log := WithContext(ctx, logger.New().WithOutput(buf), "my-project", RecordErrors())
+
+	log.Warn("WARN message")
+	log.Error("ERROR message")
+	span.End()
+
+	sc := span.SpanContext()
+	expected := `"logging.googleapis.com/trace":"projects/my-project/traces/` + sc.TraceID().String() +
+		`","logging.googleapis.com/trace_sampled":true,"logging.googleapis.com/spanId":"` + sc.SpanID().String() + `"}`
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		if !strings.HasSuffix(line, expected) {
+			t.Errorf("output %s does not end with %s", line, expected)
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	events := spans[0].Events()
+	if len(events) != 1 {
+		t.Fatalf("expected only the ERROR entry to be recorded, got %d events", len(events))
+	}
+
+	attrs := map[string]string{}
+	for _, a := range events[0].Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs[severityAttribute] != "ERROR" || attrs[messageAttribute] != "ERROR message" || attrs[functionAttribute] != "otellogger.TestWithContext" {
+		t.Errorf("unexpected event attributes %v", attrs)
+	}
+}
+
+func TestWithContextWithoutSpan(t *testing.T) {
+	log := logger.New()
+	if got := WithContext(context.Background(), log, "my-project"); got != log {
+		t.Errorf("expected the logger to be returned as is")
+	}
+}
@@ -0,0 +1,217 @@
+// Package otlp provides a logger.Sink shipping the entries to an OpenTelemetry collector over
+// OTLP/HTTP, converted into the OpenTelemetry logs data model.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bendingspoons.com/logger"
+)
+
+const (
+	logsPath = "/v1/logs"
+
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxQueueSize  = 2048
+	defaultTimeout       = 10 * time.Second
+)
+
+var (
+	// ErrQueueFull is returned by Write when the queue is full, the entry is dropped
+	ErrQueueFull = errors.New("otlp: queue is full")
+	// ErrClosed is returned by Write once the Exporter is closed
+	ErrClosed = errors.New("otlp: exporter is closed")
+)
+
+// Config configures an Exporter
+type Config struct {
+	// Endpoint is the base URL of the collector, e.g. http://localhost:4318.
+	// Records are posted to its /v1/logs path.
+	Endpoint string
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// BatchSize is the maximum number of records per request, 512 by default
+	BatchSize int
+	// FlushInterval is the maximum time records wait in the queue, 5s by default
+	FlushInterval time.Duration
+	// MaxQueueSize is the maximum number of records waiting in the queue, 2048 by default
+	MaxQueueSize int
+	// Client sends the requests, a client with a 10s timeout by default
+	Client *http.Client
+}
+
+// Exporter is a logger.Sink converting the entries into OTLP log records, which are exported in
+// batches: as soon as a batch is full, or when the flush interval is over.
+// Close must be called to export the records still in the queue.
+type Exporter struct {
+	cfg Config
+	url string
+
+	mux    sync.Mutex
+	queue  []record
+	closed bool
+
+	// exportMux makes sure batches are exported one at a time, in order
+	exportMux sync.Mutex
+
+	flush chan struct{}
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewExporter instantiates an Exporter and starts exporting in the background
+func NewExporter(cfg Config) *Exporter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = defaultMaxQueueSize
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	e := &Exporter{
+		cfg:   cfg,
+		url:   strings.TrimRight(cfg.Endpoint, "/") + logsPath,
+		flush: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+
+	e.wg.Add(1)
+	go e.run()
+
+	return e
+}
+
+// Write implements logger.Sink, it queues the payload for export
+func (e *Exporter) Write(p *logger.Payload) error {
+	r := convert(p, time.Now())
+
+	e.mux.Lock()
+	if e.closed {
+		e.mux.Unlock()
+		return ErrClosed
+	}
+	if len(e.queue) >= e.cfg.MaxQueueSize {
+		e.mux.Unlock()
+		return ErrQueueFull
+	}
+	e.queue = append(e.queue, r)
+	full := len(e.queue) >= e.cfg.BatchSize
+	e.mux.Unlock()
+
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Flush exports all the records in the queue. Records which fail to be exported are dropped.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.exportMux.Lock()
+	defer e.exportMux.Unlock()
+
+	e.mux.Lock()
+	records := e.queue
+	e.queue = nil
+	e.mux.Unlock()
+
+	for len(records) > 0 {
+		n := e.cfg.BatchSize
+		if n > len(records) {
+			n = len(records)
+		}
+
+		if err := e.export(ctx, records[:n]); err != nil {
+			return err
+		}
+		records = records[n:]
+	}
+
+	return nil
+}
+
+// Close stops the background export and exports the records still in the queue
+func (e *Exporter) Close(ctx context.Context) error {
+	e.mux.Lock()
+	if e.closed {
+		e.mux.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mux.Unlock()
+
+	close(e.done)
+	e.wg.Wait()
+
+	return e.Flush(ctx)
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+
+		if err := e.Flush(context.Background()); err != nil {
+			fmt.Printf("logger ERROR: otlp export failed: %s\n", err)
+		}
+	}
+}
+
+func (e *Exporter) export(ctx context.Context, records []record) error {
+	b, err := json.Marshal(newExportRequest(records))
+	if err != nil {
+		return fmt.Errorf("otlp: cannot marshal request: %s", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("otlp: cannot create request: %s", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.cfg.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp: cannot send request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp: collector responded %s: %s", resp.Status, body)
+	}
+
+	return nil
+}
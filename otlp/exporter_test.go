@@ -0,0 +1,158 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bendingspoons.com/logger"
+)
+
+// collector is a local stand-in for an OTLP/HTTP collector
+func collector(t *testing.T) (*httptest.Server, chan exportRequest) {
+	requests := make(chan exportRequest, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != logsPath || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "token" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header)
+		}
+
+		b, _ := ioutil.ReadAll(r.Body)
+		req := exportRequest{}
+		if err := json.Unmarshal(b, &req); err != nil {
+			t.Errorf("failed to unmarshal request %s: %s", b, err)
+		}
+		requests <- req
+	}))
+
+	return srv, requests
+}
+
+func attributes(kvs []keyValue) map[string]anyValue {
+	m := map[string]anyValue{}
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestExporter(t *testing.T) {
+	srv, requests := collector(t)
+	defer srv.Close()
+
+	exp := NewExporter(Config{
+		Endpoint:      srv.URL,
+		Headers:       map[string]string{"Authorization": "token"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+
+	log := logger.New().
+		WithOutput(ioutil.Discard).
+		WithSink(exp).
+		With(logger.Fields{"user": map[string]interface{}{"id": 42, "premium": true}}).
+		WithTrace("0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", true, "my-project")
+
+	log.Warn("first")
+	log.Error("second")
+
+	var req exportRequest
+	select {
+	case req = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a full batch to be exported")
+	}
+
+	if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("unexpected request %+v", req)
+	}
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	r := records[1]
+	if r.SeverityNumber != 17 || r.SeverityText != "ERROR" || *r.Body.StringValue != "second" {
+		t.Errorf("unexpected record %+v", r)
+	}
+	if r.TraceID != "0af7651916cd43dd8448eb211c80319c" || r.SpanID != "b7ad6b7169203331" || r.Flags != sampledFlag {
+		t.Errorf("unexpected trace in record %+v", r)
+	}
+	if r.TimeUnixNano == "" || r.ObservedTimeUnixNano == "" {
+		t.Errorf("expected the record to be timestamped %+v", r)
+	}
+
+	attrs := attributes(r.Attributes)
+	user := attributes(attrs["user"].KvlistValue.Values)
+	if *user["id"].IntValue != "42" || !*user["premium"].BoolValue {
+		t.Errorf("unexpected user attribute %+v", user)
+	}
+	if *attrs[functionAttribute].StringValue != "otlp.TestExporter" || attrs[stacktraceAttribute].StringValue == nil {
+		t.Errorf("expected the report location and stacktrace attributes, got %+v", attrs)
+	}
+
+	// Records left in the queue are exported on Close
+	log.Info("third")
+	if err := exp.Close(context.Background()); err != nil {
+		t.Fatalf("failed to close the exporter: %s", err)
+	}
+
+	req = <-requests
+	if n := len(req.ResourceLogs[0].ScopeLogs[0].LogRecords); n != 1 {
+		t.Errorf("expected 1 record on close, got %d", n)
+	}
+
+	if err := exp.Write(&logger.Payload{}); err != ErrClosed {
+		t.Errorf("expected %s once closed, got %v", ErrClosed, err)
+	}
+}
+
+func TestExporterGroupsByResource(t *testing.T) {
+	srv, requests := collector(t)
+	defer srv.Close()
+
+	exp := NewExporter(Config{Endpoint: srv.URL, Headers: map[string]string{"Authorization": "token"}})
+	for _, svc := range []string{"a", "b", "a"} {
+		exp.Write(&logger.Payload{
+			Severity:       "INFO",
+			Message:        "message",
+			ServiceContext: &logger.ServiceContext{Service: svc, Version: "1.0"},
+		})
+	}
+	if err := exp.Close(context.Background()); err != nil {
+		t.Fatalf("failed to close the exporter: %s", err)
+	}
+
+	req := <-requests
+	if len(req.ResourceLogs) != 2 {
+		t.Fatalf("expected 2 resources, got %+v", req.ResourceLogs)
+	}
+
+	for i, expected := range []struct {
+		service string
+		records int
+	}{{"a", 2}, {"b", 1}} {
+		rl := req.ResourceLogs[i]
+		if attrs := attributes(rl.Resource.Attributes); *attrs[serviceNameAttribute].StringValue != expected.service || *attrs[serviceVersionAttribute].StringValue != "1.0" {
+			t.Errorf("unexpected resource %+v", rl.Resource)
+		}
+		if n := len(rl.ScopeLogs[0].LogRecords); n != expected.records {
+			t.Errorf("expected %d records for %s, got %d", expected.records, expected.service, n)
+		}
+	}
+}
+
+func TestExporterQueueFull(t *testing.T) {
+	exp := NewExporter(Config{Endpoint: "http://127.0.0.1:0", BatchSize: 10, MaxQueueSize: 1, FlushInterval: time.Hour})
+
+	if err := exp.Write(&logger.Payload{}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := exp.Write(&logger.Payload{}); err != ErrQueueFull {
+		t.Errorf("expected %s, got %v", ErrQueueFull, err)
+	}
+}
@@ -0,0 +1,271 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bendingspoons.com/logger"
+)
+
+// The types below follow the JSON encoding of the OTLP logs data model, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+	Flags                uint32     `json:"flags,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string       `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *string       `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *keyValueList `json:"kvlistValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type keyValueList struct {
+	Values []keyValue `json:"values"`
+}
+
+const scopeName = "bendingspoons.com/logger"
+
+// Attributes set from the payload, named after the OpenTelemetry semantic conventions
+const (
+	serviceNameAttribute    = "service.name"
+	serviceVersionAttribute = "service.version"
+	stacktraceAttribute     = "exception.stacktrace"
+	functionAttribute       = "code.function"
+	filePathAttribute       = "code.filepath"
+	lineNumberAttribute     = "code.lineno"
+	operationIDAttribute    = "operation.id"
+	operationNameAttribute  = "operation.producer"
+	metricNameAttribute     = "metric.name"
+	metricValueAttribute    = "metric.value"
+	metricUnitAttribute     = "metric.unit"
+)
+
+// sampledFlag is the W3C trace flag marking a sampled trace
+const sampledFlag uint32 = 1
+
+var severityNumber = map[string]int{
+	"DEBUG":    5,
+	"INFO":     9,
+	"WARN":     13,
+	"ERROR":    17,
+	"CRITICAL": 21,
+}
+
+// record is a log record along with the resource it belongs to
+type record struct {
+	resource logger.ServiceContext
+	logRecord
+}
+
+// convert turns a payload into an OTLP log record
+func convert(p *logger.Payload, observed time.Time) record {
+	r := record{
+		logRecord: logRecord{
+			ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+			SeverityNumber:       severityNumber[p.Severity],
+			SeverityText:         p.Severity,
+			Body:                 stringValue(p.Message),
+			SpanID:               p.SpanID,
+		},
+	}
+
+	// eventTime can only be parsed with the default layout, the record is left without a time otherwise
+	if p.Timestamp != nil {
+		r.TimeUnixNano = strconv.FormatInt(time.Unix(p.Timestamp.Seconds, int64(p.Timestamp.Nanos)).UnixNano(), 10)
+	} else if t, err := time.Parse(time.RFC3339Nano, p.EventTime); err == nil {
+		r.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+	}
+	if p.ServiceContext != nil {
+		r.resource = *p.ServiceContext
+	}
+	if i := strings.LastIndex(p.Trace, "/"); p.Trace != "" {
+		r.TraceID = p.Trace[i+1:]
+	}
+	if p.TraceSampled != nil && *p.TraceSampled {
+		r.Flags = sampledFlag
+	}
+
+	attrs := map[string]interface{}{}
+	if p.Context != nil {
+		for k, v := range p.Context.Data {
+			attrs[k] = v
+		}
+		if loc := p.Context.ReportLocation; loc != nil {
+			attrs[functionAttribute] = loc.FunctionName
+			attrs[filePathAttribute] = loc.FilePath
+			attrs[lineNumberAttribute] = loc.LineNumber
+		}
+	}
+	for k, v := range p.Labels {
+		attrs[k] = v
+	}
+	if p.Stacktrace != "" {
+		attrs[stacktraceAttribute] = p.Stacktrace
+	}
+	if p.Operation != nil {
+		attrs[operationIDAttribute] = p.Operation.ID
+		attrs[operationNameAttribute] = p.Operation.Producer
+	}
+	if p.Metric != nil {
+		attrs[metricNameAttribute] = p.Metric.Name
+		attrs[metricValueAttribute] = p.Metric.Value
+		if p.Metric.Unit != "" {
+			attrs[metricUnitAttribute] = p.Metric.Unit
+		}
+		for k, v := range p.Metric.Labels {
+			attrs[k] = v
+		}
+	}
+
+	r.Attributes = keyValues(attrs)
+	return r
+}
+
+// resourceAttributes returns the resource attributes of a service context
+func resourceAttributes(sc logger.ServiceContext) []keyValue {
+	attrs := map[string]interface{}{}
+	if sc.Service != "" {
+		attrs[serviceNameAttribute] = sc.Service
+	}
+	if sc.Version != "" {
+		attrs[serviceVersionAttribute] = sc.Version
+	}
+
+	return keyValues(attrs)
+}
+
+// keyValues converts a map into attributes, sorted by key
+func keyValues(m map[string]interface{}) []keyValue {
+	return sortedKeyValues(m, toAnyValue)
+}
+
+func sortedKeyValues(m map[string]interface{}, convert func(interface{}) anyValue) []keyValue {
+	kvs := make([]keyValue, 0, len(m))
+	for k, v := range m {
+		kvs = append(kvs, keyValue{Key: k, Value: convert(v)})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+
+	return kvs
+}
+
+// toAnyValue converts an arbitrary value into an OTLP value. Values are normalised through their
+// JSON representation first, so that they are rendered the same way they are on the output.
+func toAnyValue(v interface{}) anyValue {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return stringValue(fmt.Sprintf("%v", v))
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var n interface{}
+	if err := d.Decode(&n); err != nil {
+		return stringValue(string(b))
+	}
+
+	return jsonToAnyValue(n)
+}
+
+func jsonToAnyValue(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return stringValue(v)
+	case bool:
+		return anyValue{BoolValue: &v}
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			s := string(v)
+			return anyValue{IntValue: &s}
+		}
+		f, _ := v.Float64()
+		return anyValue{DoubleValue: &f}
+	case []interface{}:
+		a := &arrayValue{Values: make([]anyValue, len(v))}
+		for i, e := range v {
+			a.Values[i] = jsonToAnyValue(e)
+		}
+		return anyValue{ArrayValue: a}
+	case map[string]interface{}:
+		return anyValue{KvlistValue: &keyValueList{Values: sortedKeyValues(v, jsonToAnyValue)}}
+	}
+
+	// null
+	return anyValue{}
+}
+
+func stringValue(s string) anyValue {
+	return anyValue{StringValue: &s}
+}
+
+// newExportRequest groups the records by resource
+func newExportRequest(records []record) *exportRequest {
+	req := &exportRequest{}
+	index := map[logger.ServiceContext]int{}
+
+	for _, r := range records {
+		i, ok := index[r.resource]
+		if !ok {
+			i = len(req.ResourceLogs)
+			index[r.resource] = i
+			req.ResourceLogs = append(req.ResourceLogs, resourceLogs{
+				Resource:  resource{Attributes: resourceAttributes(r.resource)},
+				ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}}},
+			})
+		}
+
+		sl := &req.ResourceLogs[i].ScopeLogs[0]
+		sl.LogRecords = append(sl.LogRecords, r.logRecord)
+	}
+
+	return req
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactAction is what a Redactor does with a sensitive value
+type RedactAction int
+
+const (
+	// Mask replaces the sensitive value with a placeholder
+	Mask RedactAction = iota
+	// Hash replaces the sensitive value with its HMAC-SHA256 keyed by RedactionPolicy.HashKey, so
+	// that entries can still be correlated without the value being recoverable by brute force
+	Hash
+	// Drop removes the field holding the sensitive value
+	Drop
+)
+
+const (
+	redactedValue = "[REDACTED]"
+	hashPrefix    = "hmac-sha256:"
+)
+
+// Value patterns matching the most common kinds of sensitive data
+var (
+	PhonePattern       = regexp.MustCompile(`\+[1-9]\d{6,14}\b`)
+	EmailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	CreditCardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// RedactionPolicy configures which values a Redactor considers sensitive
+type RedactionPolicy struct {
+	// Keys whose values are sensitive, matched case-insensitively
+	Keys []string
+	// KeyPatterns matching the keys whose values are sensitive
+	KeyPatterns []*regexp.Regexp
+	// ValuePatterns matching the sensitive parts of string values, whatever their key
+	ValuePatterns []*regexp.Regexp
+	// Action applied to the sensitive values
+	Action RedactAction
+	// HashKey is the secret key of the HMAC computed by the Hash action, which masks the values
+	// without it. It must be kept out of the logs, and be the same across the services whose
+	// entries are to be correlated.
+	HashKey []byte
+}
+
+// Redactor masks, hashes or drops the sensitive values found anywhere in the entry fields,
+// including nested Fields, maps and slices. Values of other types, such as structs, are left as is.
+type Redactor struct {
+	keys          map[string]bool
+	keyPatterns   []*regexp.Regexp
+	valuePatterns []*regexp.Regexp
+	action        RedactAction
+	hashKey       []byte
+}
+
+// NewRedactor instantiates and returns a Redactor enforcing the policy p
+func NewRedactor(p RedactionPolicy) *Redactor {
+	r := &Redactor{
+		keys:          map[string]bool{},
+		keyPatterns:   p.KeyPatterns,
+		valuePatterns: p.ValuePatterns,
+		action:        p.Action,
+		hashKey:       p.HashKey,
+	}
+
+	if r.action == Hash && len(r.hashKey) == 0 {
+		fmt.Println("logger ERROR: the Hash redaction requires a HashKey, masking the sensitive values instead")
+		r.action = Mask
+	}
+
+	for _, k := range p.Keys {
+		r.keys[strings.ToLower(k)] = true
+	}
+
+	return r
+}
+
+// redact returns a redacted copy of fields, fields itself is never modified. Redacting fields
+// which are already redacted leaves them as they are.
+func (r *Redactor) redact(fields Fields) Fields {
+	if fields == nil {
+		return nil
+	}
+
+	return Fields(r.redactMap(fields))
+}
+
+func (r *Redactor) redactMap(m map[string]interface{}) map[string]interface{} {
+	n := make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		if r.isSensitiveKey(k) {
+			if r.action != Drop {
+				n[k] = r.replace(fmt.Sprint(v))
+			}
+			continue
+		}
+
+		if v, ok := r.redactValue(v); ok {
+			n[k] = v
+		}
+	}
+
+	return n
+}
+
+// redactValue returns the redacted value and whether it should be kept
+func (r *Redactor) redactValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		return r.redactString(v)
+	case Fields:
+		return Fields(r.redactMap(v)), true
+	case map[string]interface{}:
+		return r.redactMap(v), true
+	case map[string]string:
+		m := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			m[k] = s
+		}
+		return r.redactMap(m), true
+	case []interface{}:
+		s := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			if e, ok := r.redactValue(e); ok {
+				s = append(s, e)
+			}
+		}
+		return s, true
+	case []string:
+		s := make([]string, 0, len(v))
+		for _, e := range v {
+			if e, ok := r.redactString(e); ok {
+				s = append(s, e.(string))
+			}
+		}
+		return s, true
+	}
+
+	return v, true
+}
+
+func (r *Redactor) redactString(s string) (interface{}, bool) {
+	if s == redactedValue || isHashed(s) {
+		return s, true
+	}
+
+	for _, p := range r.valuePatterns {
+		if !p.MatchString(s) {
+			continue
+		}
+		if r.action == Drop {
+			return nil, false
+		}
+		s = p.ReplaceAllStringFunc(s, r.replace)
+	}
+
+	return s, true
+}
+
+// redactField returns the string value of the field key redacted. A dropped value is replaced
+// by redactedValue, for the strings which cannot be left out, e.g. the ones of a message.
+func (r *Redactor) redactField(key, s string) string {
+	redacted, ok := r.redactMap(map[string]interface{}{key: s})[key]
+	if !ok {
+		return redactedValue
+	}
+	return fmt.Sprint(redacted)
+}
+
+func (r *Redactor) isSensitiveKey(k string) bool {
+	if r.keys[strings.ToLower(k)] {
+		return true
+	}
+
+	for _, p := range r.keyPatterns {
+		if p.MatchString(k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Redactor) replace(s string) string {
+	if s == redactedValue || isHashed(s) {
+		return s
+	}
+
+	if r.action == Hash {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return hashPrefix + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	return redactedValue
+}
+
+func isHashed(s string) bool {
+	if len(s) != len(hashPrefix)+2*sha256.Size || !strings.HasPrefix(s, hashPrefix) {
+		return false
+	}
+
+	_, err := hex.DecodeString(s[len(hashPrefix):])
+	return err == nil
+}
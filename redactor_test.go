@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactorMask(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	r := NewRedactor(RedactionPolicy{
+		Keys:          []string{"Password"},
+		KeyPatterns:   []*regexp.Regexp{regexp.MustCompile(`(?i)token$`)},
+		ValuePatterns: []*regexp.Regexp{PhonePattern, EmailPattern, CreditCardPattern, BearerTokenPattern},
+	})
+
+	fields := Fields{
+		"user":     "+1234567890",
+		"password": "hunter2",
+		"action":   "create-account",
+		"request": Fields{
+			"authorization": "Bearer abc.def-ghi",
+			"accessToken":   "secret",
+			"emails":        []string{"someone@example.com", "not an email"},
+		},
+		"notes": []interface{}{"card 4111 1111 1111 1111 on file", map[string]string{"to": "+391234567"}},
+	}
+	log := New().With(fields).WithOutput(buf).WithRedactor(r)
+
+	log.Info("INFO message")
+	expected := `"context":{"data":{"action":"create-account","notes":["card [REDACTED] on file",{"to":"[REDACTED]"}],"password":"[REDACTED]","request":{"accessToken":"[REDACTED]","authorization":"[REDACTED]","emails":["[REDACTED]","not an email"]},"user":"[REDACTED]"}}`
+	if got := buf.String(); !strings.Contains(got, expected) {
+		t.Errorf("output %s does not contain %s", got, expected)
+	}
+
+	// The fields of the Log must be left untouched
+	if fields["user"] != "+1234567890" || fields["request"].(Fields)["accessToken"] != "secret" {
+		t.Errorf("redaction altered the logger fields: %v", fields)
+	}
+}
+
+func TestRedactorHash(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	r := NewRedactor(RedactionPolicy{
+		Keys:    []string{"user"},
+		Action:  Hash,
+		HashKey: []byte("secret-key"),
+	})
+
+	New().With(Fields{"user": "+1234567890"}).WithOutput(buf).WithRedactor(r).Info("INFO message")
+
+	// HMAC-SHA256("secret-key", "+1234567890")
+	expected := `"user":"hmac-sha256:58931a1fe1919962cf8b47a3c2d904cccb3f05104433d1297755286c6707db95"`
+	if got := buf.String(); !strings.Contains(got, expected) {
+		t.Errorf("output %s does not contain %s", got, expected)
+	}
+
+	// Redacting again leaves the hash as it is
+	f := r.redact(Fields{"user": "+1234567890"})
+	if again := r.redact(f); again["user"] != f["user"] {
+		t.Errorf("expected the hash to be kept, got %v and %v", f["user"], again["user"])
+	}
+}
+
+func TestRedactorHashWithoutKey(t *testing.T) {
+	r := NewRedactor(RedactionPolicy{
+		Keys:   []string{"user"},
+		Action: Hash,
+	})
+
+	if f := r.redact(Fields{"user": "+1234567890"}); f["user"] != redactedValue {
+		t.Errorf("expected the value to be masked without a key, got %v", f["user"])
+	}
+}
+
+func TestRedactorAfterEncodingFallback(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	type contact struct {
+		Phone string
+		Ch    chan int
+	}
+
+	buf := new(bytes.Buffer)
+	r := NewRedactor(RedactionPolicy{
+		ValuePatterns: []*regexp.Regexp{PhonePattern},
+	})
+
+	New().With(Fields{"contact": contact{Phone: "+1234567890", Ch: make(chan int)}}).
+		WithOutput(buf).WithRedactor(r).Info("INFO message")
+
+	if got := buf.String(); strings.Contains(got, "+1234567890") || !strings.Contains(got, redactedValue) {
+		t.Errorf("expected the fallback representation to be redacted, got %s", got)
+	}
+}
+
+func TestRedactorDrop(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	r := NewRedactor(RedactionPolicy{
+		Keys:          []string{"password"},
+		ValuePatterns: []*regexp.Regexp{EmailPattern},
+		Action:        Drop,
+	})
+
+	New().With(Fields{
+		"password": "hunter2",
+		"contact":  "someone@example.com",
+		"emails":   []string{"someone@example.com", "not an email"},
+		"key":      "value",
+	}).WithOutput(buf).WithRedactor(r).Info("INFO message")
+
+	expected := `"context":{"data":{"emails":["not an email"],"key":"value"}}`
+	if got := buf.String(); !strings.Contains(got, expected) {
+		t.Errorf("output %s does not contain %s", got, expected)
+	}
+}
+
+func TestRedactorAppliesToHookFields(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	hook := &testHook{
+		levels: []Severity{INFO},
+		fire: func(e *Entry) error {
+			e.Fields["email"] = "someone@example.com"
+			return nil
+		},
+	}
+
+	New().WithOutput(buf).
+		WithRedactor(NewRedactor(RedactionPolicy{ValuePatterns: []*regexp.Regexp{EmailPattern}})).
+		WithHook(hook).
+		Info("INFO message")
+
+	if got := buf.String(); !strings.Contains(got, `"email":"[REDACTED]"`) {
+		t.Errorf("output %s should contain the redacted hook field", got)
+	}
+}
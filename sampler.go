@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+const samplerSummaryMessage = "logger: entries dropped by sampler"
+
+type samplerKey struct {
+	severity severity
+	message  string
+}
+
+// Sampler caps the volume of repetitive entries. Within every interval the first entries
+// sharing the same severity and message are logged, after that only every thereafter-th one is.
+// ERROR and CRITICAL entries are never sampled.
+//
+// Once an interval is over, the next entry going through the Sampler, whatever its severity, is
+// preceded by a WARN summary entry reporting how many entries were dropped per message. The
+// summary is not written until then: call Flush on the Log, e.g. before the process exits, to
+// write it right away.
+type Sampler struct {
+	tick       time.Duration
+	first      int
+	thereafter int
+
+	mux     sync.Mutex
+	start   time.Time
+	counts  map[samplerKey]int
+	dropped map[string]int
+	clock   Clock
+}
+
+// NewSampler instantiates and returns a Sampler which logs the first entries per interval tick,
+// then every thereafter-th one. A thereafter lower than 1 drops every entry past the first ones.
+// The same Sampler can be shared by several loggers.
+func NewSampler(tick time.Duration, first, thereafter int) *Sampler {
+	return &Sampler{
+		tick:       tick,
+		first:      first,
+		thereafter: thereafter,
+		counts:     map[samplerKey]int{},
+		dropped:    map[string]int{},
+		clock:      systemClock{},
+	}
+}
+
+// WithClock sets the clock measuring the intervals and returns the Sampler
+func (s *Sampler) WithClock(c Clock) *Sampler {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.clock = c
+	return s
+}
+
+// check reports whether an entry should be written. When an interval has just elapsed it also
+// returns the number of entries dropped per message during that interval. ERROR and CRITICAL
+// entries are always written, but still flush the counts of the elapsed interval.
+func (s *Sampler) check(sev severity, message string) (bool, map[string]int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var dropped map[string]int
+
+	now := s.clock.Now()
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if now.Sub(s.start) >= s.tick {
+		if len(s.dropped) > 0 {
+			dropped = s.dropped
+			s.dropped = map[string]int{}
+		}
+		s.counts = map[samplerKey]int{}
+		s.start = now
+	}
+
+	if sev >= ERROR {
+		return true, dropped
+	}
+
+	key := samplerKey{severity: sev, message: message}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true, dropped
+	}
+
+	s.dropped[message]++
+	return false, dropped
+}
+
+// flush returns the number of entries dropped per message since the last summary, and resets it
+func (s *Sampler) flush() map[string]int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if len(s.dropped) == 0 {
+		return nil
+	}
+	dropped := s.dropped
+	s.dropped = map[string]int{}
+	return dropped
+}
+
+// Flush writes the summary of the entries dropped by the Sampler of the Log so far, without
+// waiting for the interval to be over
+func (l *Log) Flush() {
+	if l.sampler != nil {
+		l.reportDropped(l.sampler.flush())
+	}
+}
+
+// reportDropped writes a WARN summary entry with the number of entries dropped per message
+func (l *Log) reportDropped(dropped map[string]int) {
+	if len(dropped) == 0 {
+		return
+	}
+
+	eventTime, timestamp := l.formatTime(l.clock.Now())
+	l.write(WARN, &Payload{
+		Severity:       WARN.String(),
+		EventTime:      eventTime,
+		Timestamp:      timestamp,
+		Message:        samplerSummaryMessage,
+		ServiceContext: l.serviceContext,
+		Context: &Context{
+			Data: Fields{"dropped": dropped},
+		},
+		InsertID: l.insertID(),
+		Labels:   l.labels,
+	})
+}
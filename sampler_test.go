@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSamplerFirstThenEvery(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(NewSampler(time.Minute, 2, 3))
+
+	for i := 0; i < 10; i++ {
+		log.Info("hot loop")
+	}
+
+	// 1st, 2nd, then every 3rd past the first two: 5th and 8th
+	if got := strings.Count(buf.String(), `"message":"hot loop"`); got != 4 {
+		t.Errorf("expected 4 entries, got %d: %s", got, buf)
+	}
+}
+
+func TestSamplerKeysBySeverityAndMessage(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(NewSampler(time.Minute, 1, 0))
+
+	log.Info("one")
+	log.Info("one")
+	log.Warn("one")
+	log.Info("two")
+
+	got := buf.String()
+	if n := strings.Count(got, "\n"); n != 3 {
+		t.Errorf("expected 3 entries, got %d: %s", n, got)
+	}
+}
+
+func TestSamplerExemptsErrors(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(NewSampler(time.Minute, 1, 0))
+
+	for i := 0; i < 5; i++ {
+		log.Error("downstream unavailable")
+	}
+
+	if got := strings.Count(buf.String(), `"severity":"ERROR"`); got != 5 {
+		t.Errorf("expected 5 ERROR entries, got %d", got)
+	}
+}
+
+func TestSamplerSummary(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewSampler(time.Second, 1, 0).WithClock(clock)
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(s)
+
+	for i := 0; i < 4; i++ {
+		log.Info("hot loop")
+	}
+	log.Debug("other")
+	log.Debug("other")
+
+	clock.Advance(time.Second)
+	buf.Reset()
+	log.Info("hot loop")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a summary and an entry, got %d lines: %s", len(lines), buf)
+	}
+
+	p := struct {
+		Severity string
+		Message  string
+		Context  struct {
+			Data struct {
+				Dropped map[string]int
+			}
+		}
+	}{}
+	if err := json.Unmarshal([]byte(lines[0]), &p); err != nil {
+		t.Fatalf("failed to unmarshal summary: %s", err)
+	}
+
+	if p.Severity != "WARN" || p.Message != samplerSummaryMessage {
+		t.Errorf("unexpected summary entry %s", lines[0])
+	}
+	if p.Context.Data.Dropped["hot loop"] != 3 || p.Context.Data.Dropped["other"] != 1 {
+		t.Errorf("unexpected dropped counts %v", p.Context.Data.Dropped)
+	}
+	if !strings.Contains(lines[1], `"message":"hot loop"`) {
+		t.Errorf("expected the entry to be written after the summary, got %s", lines[1])
+	}
+}
+
+func TestSamplerSummaryFlushedByErrors(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := NewSampler(time.Second, 1, 0).WithClock(clock)
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(s).WithStacktrace(false)
+
+	for i := 0; i < 3; i++ {
+		log.Info("hot loop")
+	}
+
+	// The hot loop is over, the next entry is an error
+	clock.Advance(time.Second)
+	buf.Reset()
+	log.Error("downstream unavailable")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a summary and an entry, got %d lines: %s", len(lines), buf)
+	}
+	if !strings.Contains(lines[0], samplerSummaryMessage) || !strings.Contains(lines[0], `"dropped":{"hot loop":2}`) {
+		t.Errorf("unexpected summary entry %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"severity":"ERROR"`) {
+		t.Errorf("expected the error to be written after the summary, got %s", lines[1])
+	}
+}
+
+func TestSamplerFlush(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithOutput(buf).WithSampler(NewSampler(time.Minute, 1, 0))
+
+	for i := 0; i < 3; i++ {
+		log.Info("hot loop")
+	}
+
+	// The summary is written without waiting for the interval to be over
+	buf.Reset()
+	log.Flush()
+	if !strings.Contains(buf.String(), samplerSummaryMessage) || !strings.Contains(buf.String(), `"dropped":{"hot loop":2}`) {
+		t.Errorf("unexpected summary entry %s", buf)
+	}
+
+	// Nothing is left to report
+	buf.Reset()
+	log.Flush()
+	New().Flush()
+	if buf.Len() != 0 {
+		t.Errorf("output %s should be empty", buf)
+	}
+}
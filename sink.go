@@ -0,0 +1,16 @@
+package logger
+
+// Sink receives every entry written by the loggers it is registered on, in addition to their
+// output, e.g. to ship them to another backend. Write is called while the output of the Log is
+// locked, in the order the entries are written: it must not block for long, and must not retain
+// p once it returns.
+type Sink interface {
+	Write(p *Payload) error
+}
+
+// WithSink creates a copy of a Log with an additional sink
+func (l *Log) WithSink(s Sink) *Log {
+	n := l.With(Fields{})
+	n.sinks = append(n.sinks[:len(n.sinks):len(n.sinks)], s)
+	return n
+}
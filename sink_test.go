@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type testSink struct {
+	payloads []Payload
+	err      error
+}
+
+func (s *testSink) Write(p *Payload) error {
+	s.payloads = append(s.payloads, *p)
+	return s.err
+}
+
+func TestWithSink(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	failing := &testSink{err: errors.New("unavailable")}
+	sink := &testSink{}
+
+	log := New().With(Fields{"key": "value"}).WithOutput(buf).WithSink(failing).WithSink(sink)
+	log.Info("INFO message")
+	log.WithOutput(failingWriter{}).Warn("WARN message")
+
+	// A failing sink or output does not prevent the other sinks from receiving the entries
+	if len(sink.payloads) != 2 || len(failing.payloads) != 2 {
+		t.Fatalf("expected the sinks to receive 2 entries, got %d and %d", len(sink.payloads), len(failing.payloads))
+	}
+	if p := sink.payloads[0]; p.Message != "INFO message" || p.Context.Data["key"] != "value" {
+		t.Errorf("unexpected payload %+v", p)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("expected the entries to be written to the output as well")
+	}
+}
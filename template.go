@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// templateField holds the raw template of a templated entry, to group the entries by template
+	templateField = "template"
+	// extraArgsField holds the arguments of a templated entry left over by its placeholders
+	extraArgsField = "extraArgs"
+)
+
+// renderTemplate replaces the {name} placeholders of template with args, in order, and returns
+// the message along with the fields recording the template and the value of every placeholder.
+// "{{" and "}}" stand for literal braces. The placeholders without an argument are left as they
+// are, the arguments without a placeholder are recorded under extraArgsField. The placeholders
+// named after one of these reserved fields are rendered but not recorded, their names are listed
+// under badKeysField. The LogValuer arguments are rendered through their LogValue. If r is not
+// nil, the values are redacted in the message as they would be in the fields.
+func renderTemplate(template string, args []interface{}, r *Redactor) (string, Fields) {
+	fields := Fields{templateField: template}
+
+	var bad []interface{}
+
+	var b strings.Builder
+	b.Grow(len(template))
+
+	n := 0
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		end := -1
+		if c == '{' {
+			end = strings.IndexByte(template[i+1:], '}')
+		}
+		if end <= 0 || n >= len(args) {
+			b.WriteByte(c)
+			continue
+		}
+
+		name := template[i+1 : i+1+end]
+		b.WriteString(renderValue(name, args[n], r))
+		switch name {
+		case templateField, extraArgsField, badKeysField:
+			bad = append(bad, name)
+		default:
+			fields[name] = args[n]
+		}
+		n++
+		i += end + 1
+	}
+
+	if n < len(args) {
+		fields[extraArgsField] = args[n:]
+	}
+	if len(bad) > 0 {
+		fields[badKeysField] = bad
+	}
+
+	return b.String(), fields
+}
+
+// renderValue renders the value of the placeholder name, redacted by r if not nil
+func renderValue(name string, v interface{}, r *Redactor) string {
+	var s string
+	if lv, ok := v.(LogValuer); ok && !isNilPointer(lv) {
+		v, _ := safeLogValue(lv)
+		s = fmt.Sprint(v)
+	} else {
+		s = fmt.Sprint(v)
+	}
+	if r == nil {
+		return s
+	}
+	return r.redactField(name, s)
+}
+
+// Debugt prints out a message with DEBUG severity level, rendered from a template whose
+// {name} placeholders are replaced by args in order. The value of every placeholder and the
+// template are added to the entry fields.
+func (l *Log) Debugt(template string, args ...interface{}) {
+	if !l.isValidLogLevel(DEBUG) {
+		return
+	}
+
+	l.logTemplate(DEBUG, template, args)
+}
+
+// Infot prints out a message with INFO severity level, rendered from a template.
+// See Debugt.
+func (l *Log) Infot(template string, args ...interface{}) {
+	if !l.isValidLogLevel(INFO) {
+		return
+	}
+
+	l.logTemplate(INFO, template, args)
+}
+
+// Warnt prints out a message with WARN severity level, rendered from a template.
+// See Debugt.
+func (l *Log) Warnt(template string, args ...interface{}) {
+	if !l.isValidLogLevel(WARN) {
+		return
+	}
+
+	l.logTemplate(WARN, template, args)
+}
+
+// Errort prints out a message with ERROR severity level, rendered from a template.
+// See Debugt.
+func (l *Log) Errort(template string, args ...interface{}) {
+	l.logTemplate(ERROR, template, args)
+}
+
+// Fatalt is equivalent to Errort() followed by a call to os.Exit(1).
+// It prints out a message with CRITICAL severity level
+func (l *Log) Fatalt(template string, args ...interface{}) {
+	l.logTemplate(CRITICAL, template, args)
+	os.Exit(1)
+}
+
+// logTemplate must be called straight from the exported methods for the caller to be
+// reported correctly
+func (l *Log) logTemplate(severity severity, template string, args []interface{}) {
+	message, fields := renderTemplate(template, args, l.redactor)
+	l.logEntry(1, &Entry{
+		Severity: severity,
+		Message:  message,
+		Fields:   fields,
+	})
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type panickingValuer struct{}
+
+func (panickingValuer) LogValue() interface{} {
+	panic("boom")
+}
+
+func TestRenderTemplate(t *testing.T) {
+	cases := []struct {
+		template string
+		args     []interface{}
+		message  string
+		fields   Fields
+	}{
+		{
+			"user {user} created order {order}", []interface{}{42, "o-1"},
+			"user 42 created order o-1", Fields{"user": 42, "order": "o-1"},
+		},
+		{
+			"{{literal}} and {} are kept, {missing} too", nil,
+			"{literal} and {} are kept, {missing} too", Fields{},
+		},
+		{
+			"failed: {error}", []interface{}{errors.New("timeout"), "extra"},
+			"failed: timeout", Fields{"error": errors.New("timeout"), extraArgsField: []interface{}{"extra"}},
+		},
+		{
+			"unterminated {name", []interface{}{1},
+			"unterminated {name", Fields{extraArgsField: []interface{}{1}},
+		},
+		{
+			"user {user}", []interface{}{&user{ID: 42, Email: "jane@example.com"}},
+			"user map[id:42]", Fields{"user": &user{ID: 42, Email: "jane@example.com"}},
+		},
+		{
+			"value {value}", []interface{}{panickingValuer{}},
+			"value logger: LogValue panicked: boom", Fields{"value": panickingValuer{}},
+		},
+		{
+			"{template} and {extraArgs} are reserved", []interface{}{1, 2},
+			"1 and 2 are reserved", Fields{badKeysField: []interface{}{templateField, extraArgsField}},
+		},
+	}
+
+	for _, c := range cases {
+		message, fields := renderTemplate(c.template, c.args, nil)
+		c.fields[templateField] = c.template
+		if message != c.message {
+			t.Errorf("renderTemplate(%q) message = %q, expected %q", c.template, message, c.message)
+		}
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("renderTemplate(%q) fields = %v, expected %v", c.template, fields, c.fields)
+		}
+	}
+}
+
+func TestTemplatedEntry(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	log := New().WithClock(testClock).WithOutput(buf).With(Fields{"request": "abc"})
+
+	log.Infot("user {user} created order {order}", 42, "o-1")
+
+	p := struct {
+		Message string
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil {
+		t.Fatalf("failed to unmarshal payload: %s", err)
+	}
+	if p.Message != "user 42 created order o-1" {
+		t.Errorf("unexpected message %q", p.Message)
+	}
+	expected := `{"order":"o-1","request":"abc","template":"user {user} created order {order}","user":42}`
+	if got := contextData(t, buf.Bytes()); got != expected {
+		t.Errorf("output %s does not match expected %s", got, expected)
+	}
+
+	// The fields are added in the current group, and do not leak into the Log
+	buf.Reset()
+	db := log.WithGroup("db")
+	db.Warnt("slow query on {table}", "users")
+	if got := contextData(t, buf.Bytes()); got != `{"db":{"table":"users","template":"slow query on {table}"},"request":"abc"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	db.Info("INFO message")
+	if got := contextData(t, buf.Bytes()); got != `{"request":"abc"}` {
+		t.Errorf("unexpected fields %s", got)
+	}
+
+	buf.Reset()
+	log.WithLevel(INFO).Debugt("user {user}", 42)
+	if buf.Len() != 0 {
+		t.Errorf("expected the DEBUG entry to be filtered out, got %s", buf)
+	}
+
+	log.Errort("failed for {user}", 42)
+	if !strings.Contains(buf.String(), `"functionName":"logger.TestTemplatedEntry"`) {
+		t.Errorf("invalid function name in error log: %s", buf)
+	}
+}
+
+func TestTemplatedEntryRedacted(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	buf := new(bytes.Buffer)
+	r := NewRedactor(RedactionPolicy{
+		Keys:          []string{"card"},
+		ValuePatterns: []*regexp.Regexp{PhonePattern},
+	})
+	log := New().WithClock(testClock).WithOutput(buf).WithRedactor(r)
+
+	log.Infot("call {phone} about {card} for {user}", "+1234567890", "4111", 42)
+
+	if got := buf.String(); strings.Contains(got, "+1234567890") || strings.Contains(got, "4111") {
+		t.Errorf("output %s should not contain the sensitive values", got)
+	}
+	if !strings.Contains(buf.String(), `"message":"call [REDACTED] about [REDACTED] for 42"`) {
+		t.Errorf("expected the message to be redacted, got %s", buf)
+	}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"time"
+)
+
+// Timestamp is the time of an entry as the seconds and nanoseconds since the Unix epoch,
+// as supported by Cloud Logging in place of eventTime
+type Timestamp struct {
+	Seconds int64 `json:"seconds"`
+	Nanos   int   `json:"nanos"`
+}
+
+// WithTimeFormat creates a copy of a Log formatting the eventTime with layout, see time.Format.
+// The default layout is time.RFC3339Nano, which Cloud Logging parses with sub-second precision.
+func (l *Log) WithTimeFormat(layout string) *Log {
+	n := l.With(Fields{})
+	n.timeLayout = layout
+	return n
+}
+
+// WithUTC creates a copy of a Log that converts the time of the entries to UTC, when enabled,
+// or leaves it in the local time zone
+func (l *Log) WithUTC(enabled bool) *Log {
+	n := l.With(Fields{})
+	n.utc = enabled
+	return n
+}
+
+// WithTimestampObject creates a copy of a Log that prints out the time of the entries as a
+// timestamp object with seconds and nanos, when enabled, instead of a formatted eventTime
+func (l *Log) WithTimestampObject(enabled bool) *Log {
+	n := l.With(Fields{})
+	n.timestampObject = enabled
+	return n
+}
+
+// formatTime returns the eventTime or the timestamp object of an entry at t
+func (l *Log) formatTime(t time.Time) (string, *Timestamp) {
+	if l.timestampObject {
+		return "", &Timestamp{
+			Seconds: t.Unix(),
+			Nanos:   t.Nanosecond(),
+		}
+	}
+
+	if l.utc {
+		t = t.UTC()
+	}
+
+	return t.Format(l.timeLayout), nil
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+	initConfig(DEBUG, "my-app", "1.0")
+
+	loc := time.FixedZone("CET", 3600)
+	clock := NewManualClock(time.Date(2020, 1, 1, 1, 0, 0, 5000, loc))
+
+	for _, tc := range []struct {
+		name     string
+		log      func(*Log) *Log
+		expected string
+	}{
+		{
+			name:     "default",
+			log:      func(l *Log) *Log { return l },
+			expected: `"eventTime":"2020-01-01T01:00:00.000005+01:00"`,
+		},
+		{
+			name:     "utc",
+			log:      func(l *Log) *Log { return l.WithUTC(true) },
+			expected: `"eventTime":"2020-01-01T00:00:00.000005Z"`,
+		},
+		{
+			name:     "layout",
+			log:      func(l *Log) *Log { return l.WithUTC(true).WithTimeFormat("2006-01-02 15:04:05.000") },
+			expected: `"eventTime":"2020-01-01 00:00:00.000"`,
+		},
+		{
+			name:     "timestamp object",
+			log:      func(l *Log) *Log { return l.WithTimestampObject(true) },
+			expected: `{"severity":"INFO","timestamp":{"seconds":1577836800,"nanos":5000},"message"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			tc.log(New().WithOutput(buf).WithClock(clock)).Info("INFO message")
+
+			got := buf.String()
+			if !strings.Contains(got, tc.expected) {
+				t.Errorf("output %s does not contain %s", got, tc.expected)
+			}
+		})
+	}
+}